@@ -0,0 +1,292 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// ChangeKind is how an element of a map differs between two versions.
+type ChangeKind int
+
+const (
+	ADDED ChangeKind = iota
+	REMOVED
+	MOVED
+	CHANGED
+)
+
+var changeKindNames = []string{"added", "removed", "moved", "changed"}
+
+func (k ChangeKind) String() string {
+	return changeKindNames[k]
+}
+
+// Change is one difference between two versions of a map. Old and New are
+// the element's index in each version, or -1 where it does not exist.
+type Change struct {
+	Kind    ChangeKind
+	Element string
+	Old     int
+	New     int
+	Detail  string
+}
+
+func (c Change) String() string {
+	index := c.New
+	if c.Kind == REMOVED {
+		index = c.Old
+	}
+	desc := fmt.Sprintf("%s %s %d", c.Kind, c.Element, index)
+	if c.Old >= 0 && c.New >= 0 && c.Old != c.New {
+		desc = fmt.Sprintf("%s %s %d (was %d)", c.Kind, c.Element, c.New, c.Old)
+	}
+	if c.Detail != "" {
+		desc += ": " + c.Detail
+	}
+	return desc
+}
+
+// MapDiff lists what changed between two versions of a map.
+type MapDiff struct {
+	Changes []Change
+}
+
+type lineKey struct {
+	a, b wad.Vertex
+}
+
+func lineKeyOf(m *wad.Map, l wad.LineDef) (lineKey, bool) {
+	if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+		return lineKey{}, false
+	}
+	a, b := m.Vertexes[l.Start], m.Vertexes[l.End]
+	if b.X < a.X || (b.X == a.X && b.Y < a.Y) {
+		a, b = b, a
+	}
+	return lineKey{a, b}, true
+}
+
+type thingKey struct {
+	x, y      int16
+	thingType uint16
+}
+
+func thingKeyOf(t wad.Thing) thingKey {
+	return thingKey{t.XPosition, t.YPosition, t.ThingType}
+}
+
+// Diff compares two versions of a map. Vertexes, linedefs and things are
+// matched by position, since editors renumber them freely; an element that
+// keeps its number but not its position is reported as moved. Sectors are
+// matched by number.
+func Diff(old *wad.Map, new *wad.Map) *MapDiff {
+	d := &MapDiff{}
+	d.diffVertexes(old, new)
+	d.diffLineDefs(old, new)
+	d.diffThings(old, new)
+	d.diffSectors(old, new)
+	return d
+}
+
+func (d *MapDiff) add(kind ChangeKind, element string, old int, new int, detail string) {
+	d.Changes = append(d.Changes, Change{kind, element, old, new, detail})
+}
+
+func (d *MapDiff) diffVertexes(old *wad.Map, new *wad.Map) {
+	oldAt := make(map[wad.Vertex]int)
+	for i, v := range old.Vertexes {
+		oldAt[v] = i
+	}
+	newAt := make(map[wad.Vertex]int)
+	for i, v := range new.Vertexes {
+		newAt[v] = i
+	}
+	moved := make(map[int]bool)
+	for i, v := range new.Vertexes {
+		if _, ok := oldAt[v]; ok || i >= len(old.Vertexes) {
+			continue
+		}
+		if _, ok := newAt[old.Vertexes[i]]; !ok {
+			moved[i] = true
+			o := old.Vertexes[i]
+			d.add(MOVED, "vertex", i, i, fmt.Sprintf("(%d, %d) to (%d, %d)", o.X, -o.Y, v.X, -v.Y))
+		}
+	}
+	for i, v := range new.Vertexes {
+		if _, ok := oldAt[v]; !ok && !moved[i] {
+			d.add(ADDED, "vertex", -1, i, fmt.Sprintf("(%d, %d)", v.X, -v.Y))
+		}
+	}
+	for i, v := range old.Vertexes {
+		if _, ok := newAt[v]; !ok && !moved[i] {
+			d.add(REMOVED, "vertex", i, -1, fmt.Sprintf("(%d, %d)", v.X, -v.Y))
+		}
+	}
+}
+
+func lineDefChanges(o wad.LineDef, n wad.LineDef) string {
+	changes := make([]string, 0)
+	if o.Flags != n.Flags {
+		changes = append(changes, fmt.Sprintf("flags %d to %d", o.Flags, n.Flags))
+	}
+	if o.SpecialType != n.SpecialType {
+		changes = append(changes, fmt.Sprintf("special %d to %d", o.SpecialType, n.SpecialType))
+	}
+	if o.SectorTag != n.SectorTag {
+		changes = append(changes, fmt.Sprintf("tag %d to %d", o.SectorTag, n.SectorTag))
+	}
+	return strings.Join(changes, ", ")
+}
+
+func (d *MapDiff) diffLineDefs(old *wad.Map, new *wad.Map) {
+	oldAt := make(map[lineKey]int)
+	for i, l := range old.LineDefs {
+		if k, ok := lineKeyOf(old, l); ok {
+			oldAt[k] = i
+		}
+	}
+	newAt := make(map[lineKey]int)
+	for i, l := range new.LineDefs {
+		if k, ok := lineKeyOf(new, l); ok {
+			newAt[k] = i
+		}
+	}
+	moved := make(map[int]bool)
+	for i, l := range new.LineDefs {
+		k, ok := lineKeyOf(new, l)
+		if !ok {
+			continue
+		}
+		if j, found := oldAt[k]; found {
+			if changes := lineDefChanges(old.LineDefs[j], l); changes != "" {
+				d.add(CHANGED, "linedef", j, i, changes)
+			}
+			continue
+		}
+		if i >= len(old.LineDefs) {
+			continue
+		}
+		if k, ok := lineKeyOf(old, old.LineDefs[i]); ok {
+			if _, found := newAt[k]; !found {
+				moved[i] = true
+				d.add(MOVED, "linedef", i, i, "")
+			}
+		}
+	}
+	for i, l := range new.LineDefs {
+		if k, ok := lineKeyOf(new, l); ok && !moved[i] {
+			if _, found := oldAt[k]; !found {
+				d.add(ADDED, "linedef", -1, i, "")
+			}
+		}
+	}
+	for i, l := range old.LineDefs {
+		if k, ok := lineKeyOf(old, l); ok && !moved[i] {
+			if _, found := newAt[k]; !found {
+				d.add(REMOVED, "linedef", i, -1, "")
+			}
+		}
+	}
+}
+
+func (d *MapDiff) diffThings(old *wad.Map, new *wad.Map) {
+	oldAt := make(map[thingKey]int)
+	for i, t := range old.Things {
+		oldAt[thingKeyOf(t)] = i
+	}
+	newAt := make(map[thingKey]int)
+	for i, t := range new.Things {
+		newAt[thingKeyOf(t)] = i
+	}
+	moved := make(map[int]bool)
+	for i, t := range new.Things {
+		if j, found := oldAt[thingKeyOf(t)]; found {
+			o := old.Things[j]
+			if o.Angle != t.Angle || o.Flags != t.Flags {
+				d.add(CHANGED, "thing", j, i, fmt.Sprintf("%s angle %d to %d, flags %d to %d", t.Name(), o.Angle, t.Angle, o.Flags, t.Flags))
+			}
+			continue
+		}
+		if i >= len(old.Things) {
+			continue
+		}
+		o := old.Things[i]
+		if _, found := newAt[thingKeyOf(o)]; !found && o.ThingType == t.ThingType {
+			moved[i] = true
+			d.add(MOVED, "thing", i, i, fmt.Sprintf("%s (%d, %d) to (%d, %d)", t.Name(), o.XPosition, -o.YPosition, t.XPosition, -t.YPosition))
+		}
+	}
+	for i, t := range new.Things {
+		if _, found := oldAt[thingKeyOf(t)]; !found && !moved[i] {
+			d.add(ADDED, "thing", -1, i, t.Name())
+		}
+	}
+	for i, t := range old.Things {
+		if _, found := newAt[thingKeyOf(t)]; !found && !moved[i] {
+			d.add(REMOVED, "thing", i, -1, t.Name())
+		}
+	}
+}
+
+func sectorChanges(o wad.Sector, n wad.Sector) string {
+	changes := make([]string, 0)
+	if o.FloorHeight != n.FloorHeight {
+		changes = append(changes, fmt.Sprintf("floor %d to %d", o.FloorHeight, n.FloorHeight))
+	}
+	if o.CeilingHeight != n.CeilingHeight {
+		changes = append(changes, fmt.Sprintf("ceiling %d to %d", o.CeilingHeight, n.CeilingHeight))
+	}
+	if o.FloorTexture != n.FloorTexture {
+		changes = append(changes, fmt.Sprintf("floor flat %s to %s", o.FloorTexture, n.FloorTexture))
+	}
+	if o.CeilingTexture != n.CeilingTexture {
+		changes = append(changes, fmt.Sprintf("ceiling flat %s to %s", o.CeilingTexture, n.CeilingTexture))
+	}
+	if o.LightLevel != n.LightLevel {
+		changes = append(changes, fmt.Sprintf("light %d to %d", o.LightLevel, n.LightLevel))
+	}
+	if o.SectorType != n.SectorType {
+		changes = append(changes, fmt.Sprintf("type %d to %d", o.SectorType, n.SectorType))
+	}
+	if o.TagNumber != n.TagNumber {
+		changes = append(changes, fmt.Sprintf("tag %d to %d", o.TagNumber, n.TagNumber))
+	}
+	return strings.Join(changes, ", ")
+}
+
+func (d *MapDiff) diffSectors(old *wad.Map, new *wad.Map) {
+	for i := range new.Sectors {
+		if i >= len(old.Sectors) {
+			d.add(ADDED, "sector", -1, i, "")
+		} else if changes := sectorChanges(old.Sectors[i], new.Sectors[i]); changes != "" {
+			d.add(CHANGED, "sector", i, i, changes)
+		}
+	}
+	for i := len(new.Sectors); i < len(old.Sectors); i++ {
+		d.add(REMOVED, "sector", i, -1, "")
+	}
+}
+
+// WriteSummary counts the changes to each kind of element and then lists
+// them.
+func (d *MapDiff) WriteSummary(w io.Writer) {
+	if len(d.Changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, element := range []struct{ name, plural string }{{"vertex", "vertexes"}, {"linedef", "linedefs"}, {"thing", "things"}, {"sector", "sectors"}} {
+		counts := make([]int, len(changeKindNames))
+		for _, c := range d.Changes {
+			if c.Element == element.name {
+				counts[c.Kind]++
+			}
+		}
+		fmt.Fprintf(w, "%-9s %d added, %d removed, %d moved, %d changed\n", element.plural+":", counts[ADDED], counts[REMOVED], counts[MOVED], counts[CHANGED])
+	}
+	for _, c := range d.Changes {
+		fmt.Fprintln(w, c)
+	}
+}
@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// diffMap returns a square room with a player start and an imp. Vertexes
+// and things are given with y increasing northwards, as in Doom.
+func diffMap() *wad.Map {
+	m := &wad.Map{
+		Vertexes: []wad.Vertex{{X: 0, Y: 0}, {X: 128, Y: 0}, {X: 128, Y: -128}, {X: 0, Y: -128}},
+		Sectors:  []wad.Sector{{FloorHeight: 0, CeilingHeight: 128}},
+		SideDefs: []wad.SideDef{{SectorNumber: 0}},
+		Things: []wad.Thing{
+			{XPosition: 32, YPosition: -32, ThingType: 1, Flags: 7},
+			{XPosition: 96, YPosition: -96, ThingType: 3001, Flags: 7},
+		},
+	}
+	for i := 0; i < 4; i++ {
+		m.LineDefs = append(m.LineDefs, wad.LineDef{Start: uint16(i), End: uint16((i + 1) % 4), Flags: 1, RightSideDef: 0, LeftSideDef: 0xffff})
+	}
+	return m
+}
+
+func TestDiff(t *testing.T) {
+	old := diffMap()
+	m := diffMap()
+	m.Vertexes[2] = wad.Vertex{X: 160, Y: -128}
+	m.LineDefs[0].Flags = 5
+	shotgun := wad.Thing{XPosition: 64, YPosition: -64, ThingType: 2001, Flags: 7}
+	m.Things[1] = shotgun
+	imp := old.Things[1]
+	want := []Change{
+		{MOVED, "vertex", 2, 2, "(128, 128) to (160, 128)"},
+		{CHANGED, "linedef", 0, 0, "flags 1 to 5"},
+		{MOVED, "linedef", 1, 1, ""},
+		{MOVED, "linedef", 2, 2, ""},
+		{ADDED, "thing", -1, 1, shotgun.Name()},
+		{REMOVED, "thing", 1, -1, imp.Name()},
+	}
+	if got := Diff(old, m).Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	if got := Diff(diffMap(), diffMap()).Changes; len(got) != 0 {
+		t.Errorf("Diff() of the same map = %v, want no changes", got)
+	}
+}
@@ -0,0 +1,470 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// Severity is how much a problem matters: errors break the map, warnings are
+// probably mistakes, and information is worth knowing about.
+type Severity int
+
+const (
+	INFO Severity = iota
+	WARNING
+	ERROR
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Problem is one defect found in a map. X and Y are in the map's own
+// coordinates, with Y pointing north, and only mean something when Located
+// is true.
+type Problem struct {
+	Severity Severity `json:"severity"`
+	Check    string   `json:"check"`
+	Message  string   `json:"message"`
+	Located  bool     `json:"located"`
+	X        int      `json:"x"`
+	Y        int      `json:"y"`
+}
+
+func (p Problem) String() string {
+	if !p.Located {
+		return fmt.Sprintf("%-7s %-20s %s", p.Severity, p.Check, p.Message)
+	}
+	return fmt.Sprintf("%-7s %-20s %s at (%d, %d)", p.Severity, p.Check, p.Message, p.X, p.Y)
+}
+
+// noSideDef is the sidedef number of a missing side.
+const noSideDef = 0xffff
+
+type linter struct {
+	m        *wad.Map
+	problems []Problem
+}
+
+func (l *linter) report(severity Severity, check string, format string, args ...interface{}) {
+	l.problems = append(l.problems, Problem{Severity: severity, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+// reportAt records a problem at a point given in vertex coordinates.
+func (l *linter) reportAt(x int, y int, severity Severity, check string, format string, args ...interface{}) {
+	l.problems = append(l.problems, Problem{Severity: severity, Check: check, Message: fmt.Sprintf(format, args...), Located: true, X: x, Y: -y})
+}
+
+func (l *linter) lineDefMidpoint(ld wad.LineDef) (int, int) {
+	start, end := l.m.Vertexes[ld.Start], l.m.Vertexes[ld.End]
+	return (int(start.X) + int(end.X)) / 2, (int(start.Y) + int(end.Y)) / 2
+}
+
+// Resources are the names of the textures and flats that a map may use. A
+// nil set is not checked. Unless the names are Complete, as they are when
+// they include an IWAD's, a name missing from them is only a warning, since
+// a PWAD may rely on textures from the IWAD it is played with.
+type Resources struct {
+	Textures map[string]bool
+	Flats    map[string]bool
+	Complete bool
+}
+
+// ReadResources collects the texture and flat names defined in the WADs,
+// typically an IWAD followed by the PWAD played with it.
+func ReadResources(dirs ...*wad.Directory) *Resources {
+	r := &Resources{}
+	for _, d := range dirs {
+		if d.IWAD() {
+			r.Complete = true
+		}
+		if textures, err := d.Textures(); err == nil {
+			if r.Textures == nil {
+				r.Textures = make(map[string]bool)
+			}
+			for _, t := range textures {
+				r.Textures[t.Name] = true
+			}
+		}
+		if flats := d.Namespace("F"); len(flats) > 0 {
+			if r.Flats == nil {
+				r.Flats = make(map[string]bool)
+			}
+			for _, f := range flats {
+				r.Flats[f.Name()] = true
+			}
+		}
+	}
+	return r
+}
+
+// Lint checks the map for structural defects. The resources may be nil.
+func Lint(m *wad.Map, resources *Resources) []Problem {
+	l := &linter{m: m}
+	valid := l.checkLineDefs()
+	l.checkSideDefs()
+	l.checkSectors(valid)
+	if resources != nil {
+		l.checkResources(valid, resources)
+	}
+	l.checkThings(valid)
+	l.checkTags(valid)
+	return l.problems
+}
+
+// checkLineDefs reports linedefs referring to things that do not exist, and
+// returns whether each one is safe to look at more closely.
+func (l *linter) checkLineDefs() []bool {
+	m := l.m
+	valid := make([]bool, len(m.LineDefs))
+	for i, ld := range m.LineDefs {
+		if int(ld.Start) >= len(m.Vertexes) || int(ld.End) >= len(m.Vertexes) {
+			l.report(ERROR, "vertex-index", "Linedef %d uses vertexes %d and %d, but there are only %d", i, ld.Start, ld.End, len(m.Vertexes))
+			continue
+		}
+		x, y := l.lineDefMidpoint(ld)
+		ok := true
+		if ld.RightSideDef == noSideDef {
+			l.reportAt(x, y, ERROR, "no-front-side", "Linedef %d has no front sidedef", i)
+			ok = false
+		}
+		for _, sd := range []uint16{ld.RightSideDef, ld.LeftSideDef} {
+			if sd != noSideDef && int(sd) >= len(m.SideDefs) {
+				l.reportAt(x, y, ERROR, "sidedef-index", "Linedef %d uses sidedef %d, but there are only %d", i, sd, len(m.SideDefs))
+				ok = false
+			} else if sd != noSideDef && int(m.SideDefs[sd].SectorNumber) >= len(m.Sectors) {
+				ok = false
+			}
+		}
+		start, end := m.Vertexes[ld.Start], m.Vertexes[ld.End]
+		if start == end {
+			l.reportAt(x, y, ERROR, "zero-length", "Linedef %d has zero length", i)
+		}
+		twoSided := ld.Flags&uint16(wad.TWO_SIDED) != 0
+		if twoSided && ld.LeftSideDef == noSideDef {
+			l.reportAt(x, y, WARNING, "two-sided-flag", "Linedef %d has one side but is flagged two sided", i)
+		}
+		valid[i] = ok
+	}
+	return valid
+}
+
+func (l *linter) checkSideDefs() {
+	m := l.m
+	used := make([]bool, len(m.SideDefs))
+	for _, ld := range m.LineDefs {
+		for _, sd := range []uint16{ld.RightSideDef, ld.LeftSideDef} {
+			if int(sd) < len(used) {
+				used[sd] = true
+			}
+		}
+	}
+	for i, sd := range m.SideDefs {
+		if int(sd.SectorNumber) >= len(m.Sectors) {
+			l.report(ERROR, "sector-index", "Sidedef %d uses sector %d, but there are only %d", i, sd.SectorNumber, len(m.Sectors))
+		}
+		if !used[i] {
+			l.report(WARNING, "unused-sidedef", "Sidedef %d is not used by any linedef", i)
+		}
+	}
+	// Node builders add the vertexes they split segs at after those used by
+	// linedefs, so only the vertexes before the last used one are checked.
+	usedVertexes := make([]bool, len(m.Vertexes))
+	last := -1
+	for _, ld := range m.LineDefs {
+		for _, v := range []uint16{ld.Start, ld.End} {
+			if int(v) < len(usedVertexes) {
+				usedVertexes[v] = true
+				if int(v) > last {
+					last = int(v)
+				}
+			}
+		}
+	}
+	for i := 0; i < last; i++ {
+		if !usedVertexes[i] {
+			v := m.Vertexes[i]
+			l.reportAt(int(v.X), int(v.Y), INFO, "unused-vertex", "Vertex %d is not used by any linedef", i)
+		}
+	}
+}
+
+// checkSectors reports sectors that no sidedef belongs to, and sectors whose
+// boundary does not form closed loops, which shows up as a vertex with an
+// odd number of boundary lines.
+func (l *linter) checkSectors(valid []bool) {
+	m := l.m
+	used := make([]bool, len(m.Sectors))
+	ends := make([]map[uint16]int, len(m.Sectors))
+	for i, ld := range m.LineDefs {
+		if !valid[i] {
+			continue
+		}
+		right, left := m.LineDefSectors(ld)
+		for _, s := range []int{right, left} {
+			if s >= 0 && s < len(used) {
+				used[s] = true
+			}
+		}
+		if right == left {
+			continue
+		}
+		for _, s := range []int{right, left} {
+			if s < 0 || s >= len(ends) {
+				continue
+			}
+			if ends[s] == nil {
+				ends[s] = make(map[uint16]int)
+			}
+			ends[s][ld.Start]++
+			ends[s][ld.End]++
+		}
+	}
+	for s := range m.Sectors {
+		if !used[s] {
+			l.report(WARNING, "unused-sector", "Sector %d is not used by any sidedef", s)
+			continue
+		}
+		for v, n := range ends[s] {
+			if n%2 == 1 {
+				vertex := m.Vertexes[v]
+				l.reportAt(int(vertex.X), int(vertex.Y), ERROR, "unclosed-sector", "Sector %d is not closed at vertex %d", s, v)
+				break
+			}
+		}
+	}
+}
+
+// checkResources reports walls that need a texture but have none, and
+// textures and flats that are not in the WADs.
+func (l *linter) checkResources(valid []bool, r *Resources) {
+	m := l.m
+	unknown := WARNING
+	if r.Complete {
+		unknown = ERROR
+	}
+	checkTexture := func(x, y, i int, part string, name string) {
+		if name != "-" && name != "" && r.Textures != nil && !r.Textures[strings.ToUpper(name)] {
+			l.reportAt(x, y, unknown, "unknown-texture", "Linedef %d %s texture %s is not defined", i, part, name)
+		}
+	}
+	for i, ld := range m.LineDefs {
+		if !valid[i] {
+			continue
+		}
+		x, y := l.lineDefMidpoint(ld)
+		front := m.SideDefs[ld.RightSideDef]
+		if ld.LeftSideDef == noSideDef {
+			if front.MiddleTextureName == "-" {
+				l.reportAt(x, y, ERROR, "missing-texture", "Linedef %d has no middle texture", i)
+			}
+		} else {
+			back := m.SideDefs[ld.LeftSideDef]
+			fs, bs := m.Sectors[front.SectorNumber], m.Sectors[back.SectorNumber]
+			for _, side := range []struct {
+				label    string
+				sd       wad.SideDef
+				from, to wad.Sector
+			}{{"front", front, fs, bs}, {"back", back, bs, fs}} {
+				if side.to.FloorHeight > side.from.FloorHeight && side.sd.LowerTextureName == "-" {
+					l.reportAt(x, y, WARNING, "missing-texture", "Linedef %d has no %s lower texture", i, side.label)
+				}
+				sky := strings.EqualFold(side.from.CeilingTexture, "F_SKY1") && strings.EqualFold(side.to.CeilingTexture, "F_SKY1")
+				if side.to.CeilingHeight < side.from.CeilingHeight && side.sd.UpperTextureName == "-" && !sky {
+					l.reportAt(x, y, WARNING, "missing-texture", "Linedef %d has no %s upper texture", i, side.label)
+				}
+			}
+			checkTexture(x, y, i, "back upper", back.UpperTextureName)
+			checkTexture(x, y, i, "back middle", back.MiddleTextureName)
+			checkTexture(x, y, i, "back lower", back.LowerTextureName)
+		}
+		checkTexture(x, y, i, "front upper", front.UpperTextureName)
+		checkTexture(x, y, i, "front middle", front.MiddleTextureName)
+		checkTexture(x, y, i, "front lower", front.LowerTextureName)
+	}
+	if r.Flats == nil {
+		return
+	}
+	for i, s := range m.Sectors {
+		for _, flat := range []string{s.FloorTexture, s.CeilingTexture} {
+			if !r.Flats[strings.ToUpper(flat)] && !strings.EqualFold(flat, "F_SKY1") {
+				l.report(unknown, "unknown-flat", "Sector %d flat %s is not defined", i, flat)
+			}
+		}
+	}
+}
+
+// isSolid reports whether a thing blocks movement and is checked for being
+// stuck: monsters and player starts.
+func isSolid(t wad.Thing) bool {
+	return t.IsMonster() || (t.ThingType >= 1 && t.ThingType <= 4)
+}
+
+func radius(t wad.Thing) int {
+	if info, ok := t.Info(); ok {
+		return info.Radius
+	}
+	return 16
+}
+
+// segmentCrossesBox reports whether the segment passes through the inside of
+// the box, using Liang-Barsky clipping.
+func segmentCrossesBox(x1, y1, x2, y2, minX, minY, maxX, maxY float64) bool {
+	t0, t1 := 0.0, 1.0
+	dx, dy := x2-x1, y2-y1
+	for _, edge := range [][2]float64{{-dx, x1 - minX}, {dx, maxX - x1}, {-dy, y1 - minY}, {dy, maxY - y1}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q <= 0 {
+				return false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			if r > t1 {
+				return false
+			}
+			if r > t0 {
+				t0 = r
+			}
+		} else {
+			if r < t0 {
+				return false
+			}
+			if r < t1 {
+				t1 = r
+			}
+		}
+	}
+	return t0 < t1
+}
+
+func (l *linter) checkThings(valid []bool) {
+	m := l.m
+	starts := 0
+	for i, t := range m.Things {
+		if t.ThingType == 1 {
+			starts++
+		}
+		if !isSolid(t) {
+			continue
+		}
+		r := radius(t)
+		for j := i + 1; j < len(m.Things); j++ {
+			o := m.Things[j]
+			if !isSolid(o) || t.Flags&o.Flags&7 == 0 {
+				continue
+			}
+			reach := r + radius(o)
+			dx, dy := int(t.XPosition)-int(o.XPosition), int(t.YPosition)-int(o.YPosition)
+			if dx < reach && -dx < reach && dy < reach && -dy < reach {
+				l.reportAt(int(t.XPosition), int(t.YPosition), WARNING, "overlapping-things", "Thing %d (%s) overlaps thing %d (%s)", i, t.Name(), j, o.Name())
+			}
+		}
+		for k, ld := range m.LineDefs {
+			if !valid[k] || (ld.LeftSideDef != noSideDef && ld.Flags&uint16(wad.BLOCKS_MONSTERS_AND_PLAYERS) == 0) {
+				continue
+			}
+			start, end := m.Vertexes[ld.Start], m.Vertexes[ld.End]
+			x, y, fr := float64(t.XPosition), float64(t.YPosition), float64(r)
+			if segmentCrossesBox(float64(start.X), float64(start.Y), float64(end.X), float64(end.Y), x-fr, y-fr, x+fr, y+fr) {
+				l.reportAt(int(t.XPosition), int(t.YPosition), WARNING, "stuck-thing", "Thing %d (%s) is stuck in linedef %d", i, t.Name(), k)
+				break
+			}
+		}
+	}
+	if starts == 0 {
+		l.report(ERROR, "no-player-start", "There is no player 1 start")
+	}
+	for p := uint16(2); p <= 4; p++ {
+		found := false
+		for _, t := range m.Things {
+			if t.ThingType == p {
+				found = true
+			}
+		}
+		if !found {
+			l.report(INFO, "no-coop-start", "There is no player %d start for co-op", p)
+		}
+	}
+	exits := 0
+	for _, ld := range m.LineDefs {
+		if ld.IsExit() {
+			exits++
+		}
+	}
+	for _, s := range m.Sectors {
+		if s.SectorType == 11 {
+			exits++
+		}
+	}
+	if exits == 0 {
+		l.report(WARNING, "no-exit", "There is no exit linedef or sector")
+	}
+}
+
+// tagged lists the categories of special that act on tagged sectors unless
+// they are activated manually.
+var tagged = map[wad.SpecialCategory]bool{
+	wad.DOOR: true, wad.LOCKED_DOOR: true, wad.LIFT: true, wad.PLATFORM: true,
+	wad.ELEVATOR: true, wad.FLOOR: true, wad.CEILING: true, wad.CRUSHER: true,
+	wad.STAIRS: true, wad.DONUT: true, wad.LIGHT: true, wad.TELEPORT: true,
+	wad.TEXTURE_CHANGE: true,
+}
+
+// checkTags reports specials whose tag matches nothing, and sector tags that
+// no special uses.
+func (l *linter) checkTags(valid []bool) {
+	m := l.m
+	sectorTags := make(map[uint16]bool)
+	for _, s := range m.Sectors {
+		sectorTags[s.TagNumber] = true
+	}
+	lineTags := make(map[uint16]int)
+	usedTags := make(map[uint16]bool)
+	for _, ld := range m.LineDefs {
+		lineTags[ld.SectorTag]++
+		usedTags[ld.SectorTag] = true
+	}
+	for i, ld := range m.LineDefs {
+		special, ok := ld.Special()
+		if !ok || !valid[i] || !tagged[special.Category] || special.Trigger == wad.D1 || special.Trigger == wad.DR {
+			continue
+		}
+		x, y := l.lineDefMidpoint(ld)
+		switch {
+		case ld.SectorTag == 0:
+			l.reportAt(x, y, WARNING, "zero-tag", "Linedef %d has special %d but no tag", i, ld.SpecialType)
+		case special.TagsLineDefs() && lineTags[ld.SectorTag] < 2:
+			l.reportAt(x, y, WARNING, "tag-without-target", "Linedef %d tag %d matches no other linedef", i, ld.SectorTag)
+		case !special.TagsLineDefs() && !sectorTags[ld.SectorTag]:
+			l.reportAt(x, y, WARNING, "tag-without-target", "Linedef %d tag %d matches no sector", i, ld.SectorTag)
+		}
+	}
+	for i, s := range m.Sectors {
+		if s.TagNumber != 0 && !usedTags[s.TagNumber] {
+			l.report(INFO, "unused-tag", "Sector %d tag %d is not used by any linedef", i, s.TagNumber)
+		}
+	}
+}
+
+// WriteLintReport writes one problem per line, most severe first.
+func WriteLintReport(w io.Writer, problems []Problem) {
+	for _, severity := range []Severity{ERROR, WARNING, INFO} {
+		for _, p := range problems {
+			if p.Severity == severity {
+				fmt.Fprintln(w, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Package analysis works out how a player can progress through a map.
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+const (
+	// MaxStepHeight is the highest ledge a player can walk up.
+	MaxStepHeight = 24
+	// PlayerHeight is the headroom a player needs to fit through a gap.
+	PlayerHeight = 56
+)
+
+// KeySet is the keys a player has picked up.
+type KeySet uint8
+
+var keyBits = []struct {
+	key wad.Key
+	bit KeySet
+}{
+	{wad.BLUE_CARD, 1 << 0},
+	{wad.YELLOW_CARD, 1 << 1},
+	{wad.RED_CARD, 1 << 2},
+	{wad.BLUE_SKULL, 1 << 3},
+	{wad.YELLOW_SKULL, 1 << 4},
+	{wad.RED_SKULL, 1 << 5},
+}
+
+func keyBit(k wad.Key) KeySet {
+	for _, b := range keyBits {
+		if b.key == k {
+			return b.bit
+		}
+	}
+	return 0
+}
+
+// Opens reports whether the keys are enough for a special that needs k.
+func (s KeySet) Opens(k wad.Key) bool {
+	switch k {
+	case wad.NO_KEY:
+		return true
+	case wad.BLUE_KEY:
+		return s.Opens(wad.BLUE_CARD) || s.Opens(wad.BLUE_SKULL)
+	case wad.YELLOW_KEY:
+		return s.Opens(wad.YELLOW_CARD) || s.Opens(wad.YELLOW_SKULL)
+	case wad.RED_KEY:
+		return s.Opens(wad.RED_CARD) || s.Opens(wad.RED_SKULL)
+	case wad.ANY_KEY:
+		return s != 0
+	case wad.ALL_THREE_KEYS:
+		return s.Opens(wad.BLUE_KEY) && s.Opens(wad.YELLOW_KEY) && s.Opens(wad.RED_KEY)
+	case wad.ALL_SIX_KEYS:
+		return s == 0x3f
+	}
+	return s&keyBit(k) != 0
+}
+
+func (s KeySet) String() string {
+	names := make([]string, 0)
+	for _, b := range keyBits {
+		if s&b.bit != 0 {
+			names = append(names, b.key.String())
+		}
+	}
+	if len(names) == 0 {
+		return "no keys"
+	}
+	return strings.Join(names, ", ")
+}
+
+type edge struct {
+	to  int
+	key wad.Key
+}
+
+// Reachability is which sectors a player can get to from player 1's start,
+// picking up keys along the way. Progress happens in stages: each stage
+// reaches everything it can with the keys found in the stages before.
+type Reachability struct {
+	// Start is the sector player 1 starts in, or -1 if there is no start.
+	Start int
+	// Stages holds the keys held during each stage.
+	Stages []KeySet
+	// Stage holds the stage each sector was first reached in, or -1 if it
+	// never was.
+	Stage []int
+	// Exits lists the linedefs with exit specials a player can reach.
+	Exits []int
+}
+
+// Reachable reports whether a player can get to the sector.
+func (r *Reachability) Reachable(sector int) bool {
+	return sector >= 0 && sector < len(r.Stage) && r.Stage[sector] >= 0
+}
+
+// Unreachable lists the sectors a player can never get to.
+func (r *Reachability) Unreachable() []int {
+	sectors := make([]int, 0)
+	for s, stage := range r.Stage {
+		if stage < 0 {
+			sectors = append(sectors, s)
+		}
+	}
+	return sectors
+}
+
+func isBlocking(l wad.LineDef) bool {
+	return l.Flags&uint16(wad.BLOCKS_MONSTERS_AND_PLAYERS) != 0 || l.Flags&uint16(wad.BLOCKS_EVERYTHING) != 0
+}
+
+// graph builds the moves a player can make between sectors. Floors and
+// ceilings are taken at their starting heights, except that door sectors
+// are assumed to open and lift sectors to lower to meet their neighbours.
+func graph(m *wad.Map) [][]edge {
+	doors := make(map[int]bool)
+	lifts := make(map[int]bool)
+	locks := make(map[int]wad.Key)
+	edges := make([][]edge, len(m.Sectors))
+	for _, a := range m.Actions() {
+		if a.Special.MonstersOnly {
+			continue
+		}
+		switch a.Special.Category {
+		case wad.DOOR, wad.LOCKED_DOOR:
+			for _, s := range a.Sectors {
+				doors[s] = true
+				if !a.Manual && a.Special.Key != wad.NO_KEY {
+					locks[s] = a.Special.Key
+				}
+			}
+		case wad.LIFT, wad.PLATFORM, wad.ELEVATOR:
+			for _, s := range a.Sectors {
+				lifts[s] = true
+			}
+		case wad.TELEPORT:
+			right, left := m.LineDefSectors(m.LineDefs[a.LineDef])
+			for _, from := range []int{right, left} {
+				if from < 0 || from >= len(m.Sectors) {
+					continue
+				}
+				for _, s := range a.Sectors {
+					edges[from] = append(edges[from], edge{s, wad.NO_KEY})
+				}
+			}
+		}
+	}
+	fits := func(from int, to int) bool {
+		f, t := m.Sectors[from], m.Sectors[to]
+		if lifts[from] || lifts[to] {
+			return true
+		}
+		if int(t.FloorHeight)-int(f.FloorHeight) > MaxStepHeight {
+			return false
+		}
+		if doors[to] || doors[from] {
+			return true
+		}
+		floor, ceiling := f.FloorHeight, f.CeilingHeight
+		if t.FloorHeight > floor {
+			floor = t.FloorHeight
+		}
+		if t.CeilingHeight < ceiling {
+			ceiling = t.CeilingHeight
+		}
+		return int(ceiling)-int(floor) >= PlayerHeight
+	}
+	for _, l := range m.LineDefs {
+		right, left := m.LineDefSectors(l)
+		if right < 0 || left < 0 || right == left || right >= len(m.Sectors) || left >= len(m.Sectors) || isBlocking(l) {
+			continue
+		}
+		manualKey := wad.NO_KEY
+		if special, ok := l.Special(); ok && (special.Trigger == wad.D1 || special.Trigger == wad.DR) {
+			manualKey = special.Key
+		}
+		if fits(right, left) {
+			key := locks[left]
+			if manualKey != wad.NO_KEY {
+				key = manualKey
+			}
+			edges[right] = append(edges[right], edge{left, key})
+		}
+		if fits(left, right) {
+			edges[left] = append(edges[left], edge{right, locks[right]})
+		}
+	}
+	return edges
+}
+
+// Analyse works out how far a player can get through the map from player
+// 1's start, without jumping, crushers or anything else that changes the
+// map's heights apart from doors and lifts.
+func Analyse(m *wad.Map) *Reachability {
+	r := &Reachability{Start: -1, Stage: make([]int, len(m.Sectors))}
+	for i := range r.Stage {
+		r.Stage[i] = -1
+	}
+	for _, t := range m.Things {
+		if t.ThingType == 1 {
+			r.Start = m.SectorAt(t.XPosition, t.YPosition)
+			break
+		}
+	}
+	if r.Start < 0 {
+		return r
+	}
+	keysIn := make(map[int]KeySet)
+	for _, t := range m.Things {
+		if t.Flags&16 == 16 {
+			continue
+		}
+		if k := t.Key(); k != wad.NO_KEY {
+			if s := m.SectorAt(t.XPosition, t.YPosition); s >= 0 {
+				keysIn[s] |= keyBit(k)
+			}
+		}
+	}
+	edges := graph(m)
+	var held KeySet
+	for stage := 0; ; stage++ {
+		r.Stages = append(r.Stages, held)
+		queue := make([]int, 0)
+		for s, st := range r.Stage {
+			if st >= 0 {
+				queue = append(queue, s)
+			}
+		}
+		if len(queue) == 0 {
+			r.Stage[r.Start] = stage
+			queue = append(queue, r.Start)
+		}
+		for len(queue) > 0 {
+			s := queue[0]
+			queue = queue[1:]
+			for _, e := range edges[s] {
+				if r.Stage[e.to] < 0 && held.Opens(e.key) {
+					r.Stage[e.to] = stage
+					queue = append(queue, e.to)
+				}
+			}
+		}
+		found := held
+		for s, keys := range keysIn {
+			if r.Stage[s] >= 0 {
+				found |= keys
+			}
+		}
+		if found == held {
+			break
+		}
+		held = found
+	}
+	for i, l := range m.LineDefs {
+		if !l.IsExit() {
+			continue
+		}
+		right, left := m.LineDefSectors(l)
+		if r.Reachable(right) || r.Reachable(left) {
+			r.Exits = append(r.Exits, i)
+		}
+	}
+	return r
+}
+
+// WriteReport describes what a player can reach, stage by stage, and which
+// sectors they cannot.
+func (r *Reachability) WriteReport(w io.Writer, m *wad.Map) {
+	if r.Start < 0 {
+		fmt.Fprintln(w, "No player 1 start inside a sector")
+		return
+	}
+	fmt.Fprintf(w, "Player 1 starts in sector %d\n", r.Start)
+	for i, keys := range r.Stages {
+		sectors := make([]string, 0)
+		for s, stage := range r.Stage {
+			if stage == i {
+				sectors = append(sectors, fmt.Sprintf("%d", s))
+			}
+		}
+		if len(sectors) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "With %s: %d sectors (%s)\n", keys, len(sectors), strings.Join(sectors, " "))
+	}
+	unreachable := r.Unreachable()
+	sort.Ints(unreachable)
+	if len(unreachable) > 0 {
+		sectors := make([]string, len(unreachable))
+		for i, s := range unreachable {
+			sectors[i] = fmt.Sprintf("%d", s)
+		}
+		fmt.Fprintf(w, "Unreachable: %d sectors (%s)\n", len(unreachable), strings.Join(sectors, " "))
+	}
+	missing := make([]string, 0)
+	for _, t := range m.Things {
+		if k := t.Key(); k != wad.NO_KEY && !r.Stages[len(r.Stages)-1].Opens(k) && t.Flags&16 == 0 {
+			missing = append(missing, k.String())
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(w, "Keys out of reach: %s\n", strings.Join(missing, ", "))
+	}
+	if len(r.Exits) == 0 {
+		fmt.Fprintln(w, "No exit can be reached")
+	} else {
+		fmt.Fprintf(w, "Reachable exits: %d\n", len(r.Exits))
+	}
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"math"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// Skill groups the skill levels that share the same things.
+type Skill int
+
+const (
+	EASY Skill = iota
+	MEDIUM
+	HARD
+)
+
+var skillNames = []string{"easy", "medium", "hard"}
+var skillFlags = []uint16{1, 2, 4}
+
+func (s Skill) String() string {
+	return skillNames[s]
+}
+
+// Mode is the kind of game being played.
+type Mode int
+
+const (
+	SINGLE_PLAYER Mode = iota
+	COOPERATIVE
+	DEATHMATCH
+)
+
+var modeNames = []string{"single", "coop", "deathmatch"}
+
+func (m Mode) String() string {
+	return modeNames[m]
+}
+
+// appearsIn reports whether the thing is spawned at the given skill level
+// and in the given mode. Flags 32 and 64 are Boom's "not in deathmatch" and
+// "not in co-op".
+func appearsIn(t wad.Thing, skill Skill, mode Mode) bool {
+	if t.Flags&skillFlags[skill] == 0 {
+		return false
+	}
+	switch mode {
+	case SINGLE_PLAYER:
+		return t.Flags&16 == 0
+	case COOPERATIVE:
+		return t.Flags&64 == 0
+	case DEATHMATCH:
+		return t.Flags&32 == 0
+	}
+	return false
+}
+
+// ammoDamage is roughly how much damage the ammo given by each pickup can
+// do, from the average damage of the weapon that fires it: 10 per bullet,
+// 70 per shell, 100 per rocket and 20 per cell.
+var ammoDamage = map[uint16]int{
+	8:    10*10 + 4*70 + 1*100 + 20*20,
+	17:   100 * 20,
+	82:   8 * 70,
+	2001: 8 * 70,
+	2002: 20 * 10,
+	2003: 2 * 100,
+	2004: 40 * 20,
+	2006: 40 * 20,
+	2007: 10 * 10,
+	2008: 4 * 70,
+	2010: 1 * 100,
+	2046: 5 * 100,
+	2047: 20 * 20,
+	2048: 50 * 10,
+	2049: 20 * 70,
+}
+
+// countedItems are the things that count towards the items total on the
+// intermission screen, those flagged MF_COUNTITEM in the game.
+var countedItems = map[uint16]bool{
+	83:   true, // Megasphere
+	2013: true, // Supercharge
+	2014: true, // Health bonus
+	2015: true, // Armor bonus
+	2022: true, // Invulnerability
+	2023: true, // Berserk
+	2024: true, // Partial invisibility
+	2026: true, // Computer area map
+	2045: true, // Light amplification visor
+}
+
+// Count is what a player meets at one skill level in one mode. Monsters is
+// the kill total shown on the intermission screen, which leaves out lost
+// souls, while MonsterHP includes them.
+type Count struct {
+	Skill       Skill   `json:"skill"`
+	Mode        Mode    `json:"mode"`
+	Monsters    int     `json:"monsters"`
+	Items       int     `json:"items"`
+	MonsterHP   int     `json:"monster_hp"`
+	AmmoDamage  int     `json:"ammo_damage"`
+	AmmoHPRatio float64 `json:"ammo_hp_ratio"`
+}
+
+// Stats summarises a map.
+type Stats struct {
+	Map     string  `json:"map"`
+	Counts  []Count `json:"counts"`
+	Secrets int     `json:"secrets"`
+	Exits   int     `json:"exits"`
+	Area    float64 `json:"area"`
+	MinX    int     `json:"min_x"`
+	MinY    int     `json:"min_y"`
+	MaxX    int     `json:"max_x"`
+	MaxY    int     `json:"max_y"`
+}
+
+// SectorArea returns the area of the sector in square map units.
+func SectorArea(m *wad.Map, sector int) float64 {
+	area := 0.0
+	for _, l := range m.SectorBoundary(sector) {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+		a := float64(start.X)*float64(end.Y) - float64(end.X)*float64(start.Y)
+		if right, _ := m.LineDefSectors(l); right != sector {
+			a = -a
+		}
+		area += a
+	}
+	return math.Abs(area) / 2
+}
+
+// MapStats counts what is in the map at every skill level and in every mode.
+// Its bounding box is in the map's own coordinates, with Y pointing north.
+func MapStats(m *wad.Map, name string) *Stats {
+	s := &Stats{Map: name}
+	for _, skill := range []Skill{EASY, MEDIUM, HARD} {
+		for _, mode := range []Mode{SINGLE_PLAYER, COOPERATIVE, DEATHMATCH} {
+			c := Count{Skill: skill, Mode: mode}
+			for _, t := range m.Things {
+				if !appearsIn(t, skill, mode) {
+					continue
+				}
+				if t.IsMonster() {
+					// Lost souls are not flagged MF_COUNTKILL.
+					if t.ThingType != 3006 {
+						c.Monsters++
+					}
+					c.MonsterHP += t.Health()
+				}
+				if countedItems[t.ThingType] {
+					c.Items++
+				}
+				c.AmmoDamage += ammoDamage[t.ThingType]
+			}
+			if c.MonsterHP > 0 {
+				c.AmmoHPRatio = float64(c.AmmoDamage) / float64(c.MonsterHP)
+			}
+			s.Counts = append(s.Counts, c)
+		}
+	}
+	for i, sector := range m.Sectors {
+		if sector.SectorType == 9 {
+			s.Secrets++
+		}
+		s.Area += SectorArea(m, i)
+	}
+	for _, l := range m.LineDefs {
+		if l.IsExit() {
+			s.Exits++
+		}
+	}
+	for i, v := range m.Vertexes {
+		// Negate as an int, as -v.Y wraps for -32768.
+		x, y := int(v.X), -int(v.Y)
+		if i == 0 || x < s.MinX {
+			s.MinX = x
+		}
+		if i == 0 || x > s.MaxX {
+			s.MaxX = x
+		}
+		if i == 0 || y < s.MinY {
+			s.MinY = y
+		}
+		if i == 0 || y > s.MaxY {
+			s.MaxY = y
+		}
+	}
+	return s
+}
+
+func (s Skill) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
@@ -0,0 +1,32 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// The kill and item totals follow the game's MF_COUNTKILL and MF_COUNTITEM
+// flags: lost souls and radiation suits are left out.
+func TestMapStatsTotals(t *testing.T) {
+	m := &wad.Map{}
+	for _, thingType := range []uint16{3001, 3006, 3006, 2014, 2025, 83, 2001} {
+		m.Things = append(m.Things, wad.Thing{ThingType: thingType, Flags: 7})
+	}
+	// Only in multiplayer, so not counted in single player.
+	m.Things = append(m.Things, wad.Thing{ThingType: 3002, Flags: 7 | 16})
+	for _, c := range MapStats(m, "MAP01").Counts {
+		if c.Mode != SINGLE_PLAYER {
+			continue
+		}
+		if c.Monsters != 1 {
+			t.Errorf("skill %v: %d monsters, want 1", c.Skill, c.Monsters)
+		}
+		if c.MonsterHP != 60+2*100 {
+			t.Errorf("skill %v: monster hit points %d, want %d", c.Skill, c.MonsterHP, 60+2*100)
+		}
+		if c.Items != 2 {
+			t.Errorf("skill %v: %d items, want 2", c.Skill, c.Items)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache keeps the most recently used values, up to a fixed total size.
+// Each value is added with its size, which may be a count or a number of
+// bytes. It is safe to use from several goroutines.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	used     int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+	size  int
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the value stored under key, marking it as the most recently
+// used.
+func (c *lruCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// Add stores value, of the given size, under key, dropping the least
+// recently used values until the cache is within its capacity. A value
+// larger than the whole cache is not kept.
+func (c *lruCache) Add(key string, value interface{}, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*lruEntry)
+		c.used += size - entry.size
+		entry.value, entry.size = value, size
+		c.order.MoveToFront(e)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, size: size})
+		c.used += size
+	}
+	for c.used > c.capacity {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.used -= entry.size
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/svg"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff old_wad_file new_wad_file map_name",
+	Short: "Draw the new version of a map with what changed since the old version highlighted, and summarise the changes on stderr",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldFile, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer oldFile.Close()
+		newFile, err := wad.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer newFile.Close()
+		old, err := oldFile.LoadMap(cmd.Context(), args[2])
+		if err != nil {
+			return err
+		}
+		m, err := newFile.LoadMap(cmd.Context(), args[2])
+		if err != nil {
+			return err
+		}
+		diff := analysis.Diff(old, m)
+		diff.WriteSummary(os.Stderr)
+		opts.WadName = filepath.Base(args[1])
+		opts.MapName = args[2]
+		opts.Resources = newFile.Directory
+		opts.Previous = old
+		opts.Diff = diff
+		return writeOutput(diffOutput, func(w io.Writer) error {
+			svg.Render(w, m, opts)
+			return nil
+		})
+	},
+}
+
+var diffOutput string
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Write the SVG to this file instead of stdout")
+	rootCmd.AddCommand(diffCmd)
+}
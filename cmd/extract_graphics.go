@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var extractGraphicsCmd = &cobra.Command{
+	Use:   "extract-graphics wad_file output_dir",
+	Short: "Extract patches, sprites and other graphics from a WAD file as PNGs",
+	Long: `Extract patches, sprites and other graphics from a WAD file as PNGs.
+
+Patches, sprites and the remaining graphics (menus, status bar, ...) are
+written to separate directories below output_dir. The offsets of each
+picture are kept in a grAb chunk of the PNG.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		d := f.Directory
+		palettes, err := d.Palettes()
+		if err != nil {
+			return err
+		}
+		if extractPalette < 0 || extractPalette >= len(palettes) {
+			return fmt.Errorf("palette %d out of range, the WAD has %d palettes", extractPalette, len(palettes))
+		}
+		pal := palettes[extractPalette]
+		groups := []struct {
+			dir   string
+			lumps []*wad.LumpPtr
+		}{
+			{"patches", d.Namespace("P")},
+			{"sprites", d.Namespace("S")},
+			{"graphics", d.Global()},
+		}
+		for _, g := range groups {
+			dir := filepath.Join(args[1], g.dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			count := 0
+			for _, l := range g.lumps {
+				p, err := wad.DecodePicture(d.ReadLump(l))
+				if err != nil {
+					// Outside of the patch and sprite namespaces plenty of
+					// lumps are not pictures at all.
+					if g.dir != "graphics" {
+						logging.Warn("Skipping graphic", "lump", l.Name(), "error", err)
+					}
+					continue
+				}
+				fileName, err := joinLocal(dir, pictureFileName(l.Name()))
+				if err != nil {
+					return err
+				}
+				if err := writePicture(fileName, p, pal); err != nil {
+					return err
+				}
+				count++
+			}
+			logging.Info("Extracted graphics", "count", count, "dir", g.dir)
+		}
+		return nil
+	},
+}
+
+var extractPalette int
+
+func init() {
+	extractGraphicsCmd.Flags().IntVar(&extractPalette, "palette", 0, "Index of the PLAYPAL palette to use")
+	rootCmd.AddCommand(extractGraphicsCmd)
+}
+
+// pictureFileName maps a lump name to a file name, replacing the backslash
+// some sprite names use with the caret used by other WAD tools. Slashes,
+// control characters and leading dots become underscores, so that a name
+// from the WAD cannot reach outside the directory it is written to.
+func pictureFileName(lumpName string) string {
+	name := []rune(lumpName)
+	for i, r := range name {
+		switch {
+		case r == '\\':
+			name[i] = '^'
+		case r == '/' || r < ' ':
+			name[i] = '_'
+		}
+	}
+	for i := 0; i < len(name) && name[i] == '.'; i++ {
+		name[i] = '_'
+	}
+	return string(name) + ".png"
+}
+
+func writePicture(fileName string, p *wad.Picture, pal wad.Palette) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := p.WritePNG(f, pal); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
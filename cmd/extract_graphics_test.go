@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+func TestJoinLocal(t *testing.T) {
+	dir := filepath.Join("out", "graphics")
+	for _, name := range []string{"", ".", "..", "../x", "a/../../x", "/etc/passwd"} {
+		if path, err := joinLocal(dir, name); err == nil {
+			t.Errorf("joinLocal(%q, %q) = %q, want an error", dir, name, path)
+		}
+	}
+	for _, name := range []string{"x.png", "a/x.png", "..x.png", "a/../x.png"} {
+		if _, err := joinLocal(dir, name); err != nil {
+			t.Errorf("joinLocal(%q, %q) returned %v", dir, name, err)
+		}
+	}
+}
+
+func TestPictureFileName(t *testing.T) {
+	for name, want := range map[string]string{
+		"TROOA1":   "TROOA1.png",
+		"VILE[1\\": "VILE[1^.png",
+		"../../x":  "___.._x.png",
+		"..":       "__.png",
+		"A\x00B/C": "A_B_C.png",
+	} {
+		if got := pictureFileName(name); got != want {
+			t.Errorf("pictureFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// A lump named to climb out of the output directory is written inside it,
+// under its upper-cased name.
+func TestExtractGraphicsHostileName(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// A one pixel picture: its header, the offset of its only column, and
+	// one post holding one pixel.
+	picture := []byte{1, 0, 1, 0, 0, 0, 0, 0, 12, 0, 0, 0, 0, 1, 0, 0, 0, 0xff}
+	buf := &bytes.Buffer{}
+	lumps := []wad.Lump{{Name: "PLAYPAL", Data: make([]byte, 768)}, {Name: "../../x", Data: picture}}
+	if err := wad.WriteWAD(buf, "PWAD", lumps); err != nil {
+		t.Fatal(err)
+	}
+	wadFile := filepath.Join(tmp, "hostile.wad")
+	if err := ioutil.WriteFile(wadFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(tmp, "a", "out")
+	if err := extractGraphicsCmd.RunE(extractGraphicsCmd, []string{wadFile, outputDir}); err != nil {
+		t.Fatalf("extract-graphics: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "a", "X.png")); err == nil {
+		t.Errorf("picture was written outside the output directory")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "graphics", "___.._X.png")); err != nil {
+		t.Errorf("picture was not written inside the output directory: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint wad_file [map_name]",
+	Short: "Check the maps in a WAD file for structural defects",
+	Args:  cobra.RangeArgs(1, 2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if lintFormat != "text" && lintFormat != "json" {
+			return fmt.Errorf("invalid --format %q, must be text or json", lintFormat)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		resources := analysis.ReadResources(f.Directory)
+		if lintIWAD != "" {
+			base, err := wad.Open(lintIWAD)
+			if err != nil {
+				return err
+			}
+			defer base.Close()
+			resources = analysis.ReadResources(base.Directory, f.Directory)
+		}
+		maps := f.Maps()
+		if len(args) > 1 {
+			maps = []string{args[1]}
+		}
+		results := make(map[string][]analysis.Problem)
+		for _, name := range maps {
+			m, err := f.LoadMap(cmd.Context(), name)
+			if err != nil {
+				return err
+			}
+			results[name] = analysis.Lint(m, resources)
+		}
+		if lintFormat == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(results)
+		}
+		for _, name := range maps {
+			fmt.Printf("%s: %d problems\n", name, len(results[name]))
+			analysis.WriteLintReport(os.Stdout, results[name])
+		}
+		return nil
+	},
+}
+
+var (
+	lintFormat string
+	lintIWAD   string
+)
+
+func init() {
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Output format (text, json)")
+	lintCmd.Flags().StringVar(&lintIWAD, "iwad", "", "IWAD file the WAD is played with, so that missing textures and flats are errors")
+	rootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macripps/wad2svg/mesh"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var meshCmd = &cobra.Command{
+	Use:   "mesh wad_file map_name output_file",
+	Short: "Write a map as a 3D model in OBJ or binary glTF",
+	Long: `Write a map as a 3D model in OBJ or binary glTF.
+
+Floors and ceilings are filled in at their heights, and walls are built
+between them: the middle of one sided linedefs, and the upper and lower
+walls where two sided linedefs separate sectors of different heights. The
+model is y-up in Doom's map units.
+
+A .glb file has its textures embedded. An .obj file is written along with
+an .mtl file of the same name, and its textures as PNGs in a directory
+named after it with _textures on the end.`,
+	Args: cobra.ExactArgs(3),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if meshFormat == "" {
+			meshFormat = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[2])), ".")
+		}
+		if meshFormat != "obj" && meshFormat != "glb" {
+			return fmt.Errorf("invalid --format %q, must be obj or glb", meshFormat)
+		}
+		return rootCmd.PreRunE(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m, err := f.LoadMap(cmd.Context(), args[1])
+		if err != nil {
+			return err
+		}
+		var d *wad.Directory
+		if meshTextures {
+			d = f.Directory
+		}
+		model, err := mesh.Build(m, d)
+		if err != nil {
+			return err
+		}
+		output := args[2]
+		if meshFormat == "glb" {
+			return writeFileAtomic(output, model.WriteGLB)
+		}
+		return writeOBJ(model, output)
+	},
+}
+
+var meshFormat string
+var meshTextures bool
+
+func init() {
+	meshCmd.Flags().StringVar(&meshFormat, "format", "", "Model format (obj, glb), by default inferred from the output_file extension")
+	meshCmd.Flags().BoolVar(&meshTextures, "textures", true, "If true, texture the model with the flats and wall textures from the WAD")
+	rootCmd.AddCommand(meshCmd)
+}
+
+// writeOBJ writes the model to output, its materials to an .mtl file beside
+// it and their textures to a directory beside that.
+func writeOBJ(model *mesh.Mesh, output string) error {
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+	mtlName := filepath.Base(base) + ".mtl"
+	textureDir := filepath.Base(base) + "_textures"
+	textures := make([]*mesh.Material, 0)
+	err := writeFileAtomic(filepath.Join(filepath.Dir(output), mtlName), func(w io.Writer) error {
+		return model.WriteMTL(w, func(m *mesh.Material) string {
+			textures = append(textures, m)
+			// MTL files use forward slashes whatever the platform.
+			return textureDir + "/" + pictureFileName(m.Name)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if len(textures) > 0 {
+		dir := filepath.Join(filepath.Dir(output), textureDir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		for _, m := range textures {
+			fileName, err := joinLocal(dir, pictureFileName(m.Name))
+			if err != nil {
+				return err
+			}
+			if err := writeFileAtomic(fileName, m.WritePNG); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(output, func(w io.Writer) error {
+		return model.WriteOBJ(w, mtlName)
+	})
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/macripps/wad2svg/dxf"
+	"github.com/macripps/wad2svg/geojson"
+	"github.com/macripps/wad2svg/svg"
+	"github.com/macripps/wad2svg/wad"
+)
+
+// outputFormats are the formats a map can be written in, by name.
+var outputFormats = map[string]func(w io.Writer, m *wad.Map, opts *svg.RenderOpts) error{
+	"dxf": func(w io.Writer, m *wad.Map, opts *svg.RenderOpts) error {
+		return dxf.Write(w, m)
+	},
+	"svg": func(w io.Writer, m *wad.Map, opts *svg.RenderOpts) error {
+		svg.Render(w, m, opts)
+		return nil
+	},
+	"png": svg.RenderPNG,
+	"geojson": func(w io.Writer, m *wad.Map, opts *svg.RenderOpts) error {
+		return geojson.Write(w, m, opts.WadName+" - "+opts.MapName)
+	},
+}
+
+// formatExtensions maps output file extensions to the format they imply.
+var formatExtensions = map[string]string{
+	".svg":     "svg",
+	".png":     "png",
+	".geojson": "geojson",
+	".dxf":     "dxf",
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// outputFormat returns the format to write in: the one asked for, or else
+// the one implied by the output file's extension, or else SVG.
+func outputFormat(format string, output string) (string, error) {
+	if format == "" && output != "" && output != "-" {
+		ext := strings.ToLower(filepath.Ext(output))
+		var ok bool
+		if format, ok = formatExtensions[ext]; !ok {
+			return "", fmt.Errorf("cannot tell the output format from %q, use --format", output)
+		}
+	}
+	if format == "" {
+		format = "svg"
+	}
+	if _, ok := outputFormats[format]; !ok {
+		return "", fmt.Errorf("invalid --format %q, must be one of %s", format, formatNames())
+	}
+	return format, nil
+}
+
+// writeOutput calls write with stdout when output is empty or "-", and
+// otherwise with a file that replaces output only once it is complete.
+func writeOutput(output string, write func(w io.Writer) error) error {
+	if output == "" || output == "-" {
+		return write(os.Stdout)
+	}
+	return writeFileAtomic(output, write)
+}
+
+// joinLocal joins dir and name, refusing a name, perhaps taken from a WAD,
+// that is absolute or would reach outside dir.
+func joinLocal(dir string, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || filepath.IsAbs(name) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q is not a file name inside %s", name, dir)
+	}
+	return path, nil
+}
+
+// writeFileAtomic writes to a temporary file next to fileName and renames it
+// into place, so that readers never see a partly written file and a failed
+// write leaves any previous file alone.
+func writeFileAtomic(fileName string, write func(w io.Writer) error) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var reachCmd = &cobra.Command{
+	Use:   "reach wad_file map_name",
+	Short: "Report which sectors a player can reach, and with which keys",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m, err := f.LoadMap(cmd.Context(), args[1])
+		if err != nil {
+			return err
+		}
+		analysis.Analyse(m).WriteReport(os.Stdout, m)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reachCmd)
+}
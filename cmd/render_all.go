@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"text/template"
+
+	"github.com/macripps/wad2svg/svg"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var renderAllCmd = &cobra.Command{
+	Use:   "render-all wad_file output_dir",
+	Short: "Render every map in a WAD file to its own SVG, and write an index page",
+	Args:  cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if renderAllJobs < 1 {
+			return fmt.Errorf("invalid --jobs %d, must be at least 1", renderAllJobs)
+		}
+		if _, err := template.New("name").Parse(renderAllName); err != nil {
+			return fmt.Errorf("invalid --name: %v", err)
+		}
+		return rootCmd.PreRunE(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		outputDir := args[1]
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return err
+		}
+		nameTemplate := template.Must(template.New("name").Parse(renderAllName))
+		maps := f.Maps()
+		fileNames := make([]string, len(maps))
+		errs := make([]error, len(maps))
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < renderAllJobs; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					fileNames[i], errs[i] = renderMap(cmd.Context(), f, filepath.Base(args[0]), maps[i], outputDir, nameTemplate)
+				}
+			}()
+		}
+		for i := range maps {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		for i, err := range errs {
+			if err != nil {
+				return fmt.Errorf("rendering %s: %v", maps[i], err)
+			}
+		}
+		return writeIndex(filepath.Join(outputDir, "index.html"), filepath.Base(args[0]), maps, fileNames)
+	},
+}
+
+var renderAllJobs int
+var renderAllName string
+
+func init() {
+	renderAllCmd.Flags().IntVar(&renderAllJobs, "jobs", runtime.NumCPU(), "How many maps to render at once")
+	renderAllCmd.Flags().StringVar(&renderAllName, "name", "{{.Map}}.svg", "Template for output file names, given .Wad (without extension) and .Map")
+	rootCmd.AddCommand(renderAllCmd)
+}
+
+// renderMap renders one map with a copy of the command line options and
+// returns the file name it was written to, relative to the output directory.
+func renderMap(ctx context.Context, f *wad.WAD, wadName string, mapName string, outputDir string, nameTemplate *template.Template) (string, error) {
+	name := &bytes.Buffer{}
+	err := nameTemplate.Execute(name, struct{ Wad, Map string }{wadName[:len(wadName)-len(filepath.Ext(wadName))], mapName})
+	if err != nil {
+		return "", err
+	}
+	// The map name comes from the WAD, so it may try to climb out of the
+	// output directory.
+	path, err := joinLocal(outputDir, name.String())
+	if err != nil {
+		return "", err
+	}
+	m, err := f.LoadMap(ctx, mapName)
+	if err != nil {
+		return "", err
+	}
+	mapOpts := *opts
+	mapOpts.WadName = wadName
+	mapOpts.MapName = mapName
+	mapOpts.Resources = f.Directory
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		svg.Render(w, m, &mapOpts)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return name.String(), nil
+}
+
+func writeIndex(fileName string, wadName string, maps []string, fileNames []string) error {
+	return writeFileAtomic(fileName, func(out io.Writer) error {
+		fmt.Fprintln(out, "<!DOCTYPE html>")
+		fmt.Fprintf(out, "<html>\n<head>\n  <meta charset=\"utf-8\">\n  <title>%s</title>\n</head>\n<body>\n", html.EscapeString(wadName))
+		fmt.Fprintf(out, "  <h1>%s</h1>\n", html.EscapeString(wadName))
+		for i, name := range maps {
+			link := html.EscapeString(filepath.ToSlash(fileNames[i]))
+			fmt.Fprintf(out, "  <figure>\n    <a href=\"%s\"><img src=\"%s\" alt=\"%s\" width=\"320\"></a>\n    <figcaption>%s</figcaption>\n  </figure>\n", link, link, html.EscapeString(name), html.EscapeString(name))
+		}
+		_, err := fmt.Fprintln(out, "</body>\n</html>")
+		return err
+	})
+}
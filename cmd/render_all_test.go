@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// A map marker named to climb out of the output directory is refused.
+func TestRenderMapHostileName(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lumps := []wad.Lump{{Name: "../../A"}, {Name: "THINGS"}, {Name: "LINEDEFS"}}
+	if err := wad.WriteWAD(buf, "PWAD", lumps); err != nil {
+		t.Fatal(err)
+	}
+	f, err := wad.NewWAD(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := template.Must(template.New("name").Parse("{{.Map}}.svg"))
+	if fileName, err := renderMap(context.Background(), f, "hostile.wad", f.Maps()[0], "out", name); err == nil {
+		t.Errorf("renderMap wrote %q, want an error", fileName)
+	}
+}
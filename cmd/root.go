@@ -1,19 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/macripps/wad2svg/logging"
 	"github.com/macripps/wad2svg/svg"
 	"github.com/macripps/wad2svg/wad"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "wad2svg wad_file map_name",
 	Short: "wad2svg generates SVG files from Doom and Doom2 WAD files",
 	Args:  cobra.ExactArgs(2),
+	// Execute prints the error itself.
+	SilenceErrors: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		var comps []string
 		if len(args) == 0 {
@@ -24,37 +30,118 @@ var rootCmd = &cobra.Command{
 		}
 		return comps, cobra.ShellCompDirectiveDefault
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if verbose && quiet {
+			return fmt.Errorf("--verbose and --quiet cannot be used together")
+		}
+		// The arguments are valid by now, so errors from here on are not
+		// about how the command was used.
+		cmd.SilenceUsage = true
+		if verbose {
+			logging.SetLevel(logging.DEBUG)
+		} else if quiet {
+			logging.SetLevel(logging.ERROR)
+		}
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateRenderOpts(opts)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(outputFormatName, outputFile)
+		if err != nil {
+			return err
+		}
 		var fileName = args[0]
 		opts.WadName = filepath.Base(fileName)
 		opts.MapName = args[1]
-		f, err := os.Open(fileName)
+		f, err := wad.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m, err := f.LoadMap(cmd.Context(), opts.MapName)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		m := &wad.Map{}
-		m.ReadFrom(f, opts.MapName)
-		svg.Render(os.Stdout, m, opts)
+		opts.Resources = f.Directory
+		return writeOutput(outputFile, func(w io.Writer) error {
+			return outputFormats[format](w, m, opts)
+		})
 	},
 }
 
 var opts *svg.RenderOpts = &svg.RenderOpts{}
+var outputFile string
+var outputFormatName string
+var verbose bool
+var quiet bool
 
 func init() {
-	rootCmd.PersistentFlags().IntVar(&opts.ImageWidth, "image_width", 1280, "Width of generated SVG image")
-	rootCmd.PersistentFlags().IntVar(&opts.ImageHeight, "image_height", 1024, "Height of generated SVG image")
+	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write to this file instead of stdout, replacing it only once complete")
+	rootCmd.Flags().StringVar(&outputFormatName, "format", "", "Output format (dxf, geojson, png, svg), by default inferred from the --output extension")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "If true, log debugging messages to stderr")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "If true, only log errors to stderr")
+	addRenderFlags(rootCmd.PersistentFlags(), opts)
 	rootCmd.PersistentFlags().BoolVar(&opts.ListMaps, "list_maps", false, "If true, print a list of maps to stderr")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderAmmo, "show_ammo", true, "Whether or not to show ammunition")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderArtifacts, "show_artifacts", true, "Whether or not to show items")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderKeys, "show_keys", true, "Whether or not to show keys")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderMonsters, "show_monsters", true, "Whether or not to show monsters")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderPowerups, "show_powerups", true, "Whether or not to show powerups")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderWeapons, "show_weapons", true, "Whether or not to show weapons")
-	rootCmd.PersistentFlags().BoolVar(&opts.RenderMultiplayer, "show_mp", false, "Whether or not to show multiplayer items")
+}
+
+// addRenderFlags adds the flags that control how maps are drawn, setting the
+// fields of o.
+func addRenderFlags(flags *pflag.FlagSet, o *svg.RenderOpts) {
+	flags.IntVar(&o.ImageWidth, "image_width", 1280, "Width of the generated image")
+	flags.IntVar(&o.ImageHeight, "image_height", 1024, "Height of the generated image")
+	flags.BoolVar(&o.RenderAmmo, "show_ammo", true, "Whether or not to show ammunition")
+	flags.BoolVar(&o.RenderArtifacts, "show_artifacts", true, "Whether or not to show items")
+	flags.BoolVar(&o.RenderKeys, "show_keys", true, "Whether or not to show keys")
+	flags.BoolVar(&o.RenderMonsters, "show_monsters", true, "Whether or not to show monsters")
+	flags.BoolVar(&o.RenderPowerups, "show_powerups", true, "Whether or not to show powerups")
+	flags.BoolVar(&o.RenderWeapons, "show_weapons", true, "Whether or not to show weapons")
+	flags.BoolVar(&o.RenderMultiplayer, "show_mp", false, "Whether or not to show multiplayer items")
+	flags.BoolVar(&o.RenderSprites, "use_sprites", false, "If true, draw things using their sprites from the WAD")
+	flags.StringVar(&o.AutomapMode, "automap", "", "Draw lines as the in-game automap does, as if fully explored (explored), with the computer area map (allmap), or at the start of the level (start)")
+	flags.BoolVar(&o.ShowLegend, "legend", false, "If true, add a legend explaining the colours used")
+	flags.BoolVar(&o.ShowScaleBar, "scale_bar", false, "If true, add a scale bar in map units")
+	flags.BoolVar(&o.ShowCompass, "compass", false, "If true, add an arrow pointing north")
+	flags.IntVar(&o.GridSize, "grid", 0, "If set, draw a grid of this size (64 or 128) aligned to the blockmap")
+	flags.BoolVar(&o.LabelSectors, "label_sectors", false, "If true, label each sector with its number")
+	flags.BoolVar(&o.LabelTags, "label_tags", false, "If true, label tagged linedefs with their tag")
+	flags.BoolVar(&o.LabelThings, "label_things", false, "If true, label things with their type")
+	flags.BoolVar(&o.TagArrows, "tag_arrows", false, "If true, draw arrows from linedefs with specials to the sectors they act on")
+	flags.BoolVar(&o.Reachability, "reachability", false, "If true, hatch over the sectors a player cannot reach from player 1's start")
+	flags.BoolVar(&o.Lint, "lint", false, "If true, circle the problems the lint command finds")
+	flags.StringVar(&o.HeightMode, "height", "", "Colour sectors by height (floor, ceiling, headroom)")
+	flags.StringVar(&o.HeightGradient, "height_gradient", svg.DefaultHeightGradient, "Comma separated #rrggbb colours from the lowest to the highest height")
+	flags.StringVar(&o.LightMode, "light", "", "Shade sectors by light level (shade, or colormap to use the WAD's COLORMAP), or mark light effects (specials)")
+	flags.BoolVar(&o.LineDefTooltips, "linedef_tooltips", false, "If true, show the properties and wall textures of each linedef on hover")
+	flags.StringVar(&o.FlatFill, "flats", "", "Fill sectors with their floor or ceiling flat from the WAD (floor, ceiling)")
+}
+
+// validateRenderOpts checks the options that only take certain values.
+func validateRenderOpts(o *svg.RenderOpts) error {
+	if o.FlatFill != "" && o.FlatFill != "floor" && o.FlatFill != "ceiling" {
+		return fmt.Errorf("invalid --flats %q, must be floor or ceiling", o.FlatFill)
+	}
+	if o.LightMode != "" && o.LightMode != "shade" && o.LightMode != "colormap" && o.LightMode != "specials" {
+		return fmt.Errorf("invalid --light %q, must be shade, colormap or specials", o.LightMode)
+	}
+	if o.HeightMode != "" && o.HeightMode != "floor" && o.HeightMode != "ceiling" && o.HeightMode != "headroom" {
+		return fmt.Errorf("invalid --height %q, must be floor, ceiling or headroom", o.HeightMode)
+	}
+	if _, err := svg.ParseGradient(o.HeightGradient); err != nil {
+		return fmt.Errorf("invalid --height_gradient: %v", err)
+	}
+	if o.AutomapMode != "" && o.AutomapMode != "explored" && o.AutomapMode != "allmap" && o.AutomapMode != "start" {
+		return fmt.Errorf("invalid --automap %q, must be explored, allmap or start", o.AutomapMode)
+	}
+	if o.GridSize != 0 && o.GridSize != 64 && o.GridSize != 128 {
+		return fmt.Errorf("invalid --grid %d, must be 64 or 128", o.GridSize)
+	}
+	return nil
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
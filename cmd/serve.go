@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/svg"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the maps in a directory of WAD files over HTTP, rendering them on demand",
+	Long: `Serve the maps in a directory of WAD files over HTTP, rendering them on demand.
+
+  /wads                            lists the WAD files in the directory
+  /wads/{wad}/maps                 lists the maps in a WAD file
+  /wads/{wad}/maps/{map}.svg       renders a map as SVG
+  /wads/{wad}/maps/{map}.png       renders a map as PNG
+  /wads/{wad}/maps/{map}.json      reports the map's statistics
+  /wads/{wad}/maps/{map}.html      browses the map's tiles with Leaflet
+  /wads/{wad}/maps/{map}/tiles.json
+                                   describes the map's tiles in TileJSON
+  /wads/{wad}/maps/{map}/tiles/{z}/{x}/{y}.png
+                                   renders an XYZ tile, also as .svg
+
+Query parameters take the names and values of the rendering flags, e.g.
+?legend=true&height=floor, and default to the flags given to serve.
+Responses carry an ETag, so browsers only fetch a map again when it changes.`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if serveWADCache < 1 || serveRenderCache < 1 {
+			return fmt.Errorf("invalid cache size, --wad_cache and --render_cache must be at least 1")
+		}
+		if info, err := os.Stat(serveDir); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", serveDir)
+		}
+		return rootCmd.PreRunE(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := newMapServer(serveDir, serveWADCache, serveRenderCache)
+		logging.Info("Serving WAD files", "dir", serveDir, "addr", serveAddr)
+		return http.ListenAndServe(serveAddr, s)
+	},
+}
+
+var serveDir string
+var serveAddr string
+var serveWADCache int
+var serveRenderCache int
+
+func init() {
+	serveCmd.Flags().StringVar(&serveDir, "dir", ".", "Directory containing the WAD files to serve")
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Address to listen on")
+	serveCmd.Flags().IntVar(&serveWADCache, "wad_cache", 8, "How many parsed WAD files to keep in memory")
+	serveCmd.Flags().IntVar(&serveRenderCache, "render_cache", 256, "How many megabytes of rendered maps to keep in memory")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// maxServedImageSize limits the size of the images the server will draw, so
+// that one request cannot use up all of its memory.
+const maxServedImageSize = 8192
+
+// tilesCacheSize is roughly how many bytes the spatial index of a map's
+// tiles takes for each linedef and thing, when counting them against the
+// render cache.
+const tilesCacheSize = 64
+
+// servedFormats are the content types of the formats a map can be served
+// in, by extension.
+var servedFormats = map[string]string{
+	".svg":  "image/svg+xml",
+	".png":  "image/png",
+	".json": "application/json",
+	".html": "text/html; charset=utf-8",
+}
+
+// mapServer serves the WAD files in a directory, keeping the most recently
+// used WAD files and rendered maps in memory.
+type mapServer struct {
+	dir     string
+	wads    *lruCache
+	renders *lruCache
+}
+
+func newMapServer(dir string, wadCache int, renderCache int) *mapServer {
+	return &mapServer{dir: dir, wads: newLRUCache(wadCache), renders: newLRUCache(renderCache << 20)}
+}
+
+// loadedWAD is a WAD file read into memory, along with the maps parsed from
+// it so far.
+type loadedWAD struct {
+	name    string
+	modTime time.Time
+	size    int64
+	wad     *wad.WAD
+	mu      sync.Mutex
+	maps    map[string]*wad.Map
+}
+
+// rendered is a map drawn in one format with one set of options.
+type rendered struct {
+	contentType string
+	etag        string
+	data        []byte
+}
+
+// httpError is an error to report to the client with the given status.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func notFound(format string, a ...interface{}) error {
+	return &httpError{http.StatusNotFound, fmt.Errorf(format, a...)}
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, a...)}
+}
+
+func (s *mapServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := s.route(w, r)
+	if err == nil {
+		return
+	}
+	status := http.StatusInternalServerError
+	if e, ok := err.(*httpError); ok {
+		status = e.status
+	}
+	if status >= 500 {
+		logging.Error("Unable to serve request", "path", r.URL.Path, "error", err)
+	} else {
+		logging.Debug("Rejected request", "path", r.URL.Path, "status", status, "error", err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (s *mapServer) route(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "wads":
+		return s.serveWADs(w, r)
+	case len(parts) == 2 && parts[0] == "wads", len(parts) == 3 && parts[0] == "wads" && parts[2] == "maps":
+		return s.serveMaps(w, r, parts[1])
+	case len(parts) == 4 && parts[0] == "wads" && parts[2] == "maps":
+		return s.serveMap(w, r, parts[1], parts[3])
+	case len(parts) == 5 && parts[0] == "wads" && parts[2] == "maps" && parts[4] == "tiles.json":
+		return s.serveTileJSON(w, r, parts[1], parts[3])
+	case len(parts) == 8 && parts[0] == "wads" && parts[2] == "maps" && parts[4] == "tiles":
+		return s.serveTile(w, r, parts[1], parts[3], parts[5:])
+	}
+	return notFound("%s not found", r.URL.Path)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func isWADFile(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".wad")
+}
+
+type wadJSON struct {
+	Name string `json:"name"`
+	Maps string `json:"maps"`
+}
+
+func (s *mapServer) serveWADs(w http.ResponseWriter, r *http.Request) error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	wads := make([]wadJSON, 0)
+	for _, f := range files {
+		if f.Mode().IsRegular() && isWADFile(f.Name()) {
+			wads = append(wads, wadJSON{f.Name(), path.Join("/wads", url.PathEscape(f.Name()), "maps")})
+		}
+	}
+	return writeJSON(w, wads)
+}
+
+type mapJSON struct {
+	Name   string `json:"name"`
+	SVG    string `json:"svg"`
+	PNG    string `json:"png"`
+	JSON   string `json:"json"`
+	Tiles  string `json:"tiles"`
+	Viewer string `json:"viewer"`
+}
+
+// mapURL returns the path below which a map is served.
+func mapURL(wadName string, mapName string) string {
+	return path.Join("/wads", url.PathEscape(wadName), "maps", url.PathEscape(mapName))
+}
+
+func (s *mapServer) serveMaps(w http.ResponseWriter, r *http.Request, wadName string) error {
+	lw, err := s.loadWAD(wadName)
+	if err != nil {
+		return err
+	}
+	maps := make([]mapJSON, 0)
+	for _, name := range lw.wad.Maps() {
+		base := mapURL(lw.name, name)
+		maps = append(maps, mapJSON{name, base + ".svg", base + ".png", base + ".json", base + "/tiles.json", base + ".html"})
+	}
+	return writeJSON(w, maps)
+}
+
+// renderFunc draws a map with the given options.
+type renderFunc func(out io.Writer, lw *loadedWAD, m *wad.Map, o *svg.RenderOpts) error
+
+func (s *mapServer) serveMap(w http.ResponseWriter, r *http.Request, wadName string, fileName string) error {
+	ext := path.Ext(fileName)
+	contentType, ok := servedFormats[ext]
+	if !ok {
+		return notFound("unknown format %q, must be .svg, .png, .json or .html", ext)
+	}
+	mapName := strings.ToUpper(strings.TrimSuffix(fileName, ext))
+	return s.serveRendered(w, r, wadName, mapName, ext, contentType, func(out io.Writer, lw *loadedWAD, m *wad.Map, o *svg.RenderOpts) error {
+		switch ext {
+		case ".svg":
+			svg.Render(out, m, o)
+		case ".png":
+			return svg.RenderPNG(out, m, o)
+		case ".json":
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(analysis.MapStats(m, mapName))
+		case ".html":
+			return writeTileViewer(out, s.tiles(lw, m, o, r.URL.Query()), lw.name+" - "+mapName, tileURL(lw.name, mapName, r.URL.Query()))
+		}
+		return nil
+	})
+}
+
+func (s *mapServer) serveTileJSON(w http.ResponseWriter, r *http.Request, wadName string, mapName string) error {
+	mapName = strings.ToUpper(mapName)
+	return s.serveRendered(w, r, wadName, mapName, "tiles.json", "application/json", func(out io.Writer, lw *loadedWAD, m *wad.Map, o *svg.RenderOpts) error {
+		return writeTileJSON(out, s.tiles(lw, m, o, r.URL.Query()), lw.name+" - "+mapName, tileURL(lw.name, mapName, r.URL.Query()))
+	})
+}
+
+func (s *mapServer) serveTile(w http.ResponseWriter, r *http.Request, wadName string, mapName string, parts []string) error {
+	ext := path.Ext(parts[2])
+	if ext != ".png" && ext != ".svg" {
+		return notFound("unknown tile format %q, must be .png or .svg", ext)
+	}
+	var zxy [3]int
+	for i, part := range []string{parts[0], parts[1], strings.TrimSuffix(parts[2], ext)} {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return notFound("invalid tile number %q", part)
+		}
+		zxy[i] = n
+	}
+	mapName = strings.ToUpper(mapName)
+	variant := fmt.Sprintf("tiles/%d/%d/%d%s", zxy[0], zxy[1], zxy[2], ext)
+	return s.serveRendered(w, r, wadName, mapName, variant, servedFormats[ext], func(out io.Writer, lw *loadedWAD, m *wad.Map, o *svg.RenderOpts) error {
+		tiles := s.tiles(lw, m, o, r.URL.Query())
+		if !tiles.Exists(zxy[0], zxy[1], zxy[2]) {
+			return notFound("tile %s is outside the map", variant)
+		}
+		return renderTile(out, tiles, ext[1:], zxy[0], zxy[1], zxy[2])
+	})
+}
+
+// tileURL returns the URL template of a map's PNG tiles, passing on the
+// rendering options in the query.
+func tileURL(wadName string, mapName string, query url.Values) string {
+	u := mapURL(wadName, mapName) + "/tiles/{z}/{x}/{y}.png"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// tiles returns the tiles of a map drawn with the options in query. They
+// are kept in the render cache, so that the spatial index is built once.
+func (s *mapServer) tiles(lw *loadedWAD, m *wad.Map, o *svg.RenderOpts, query url.Values) *svg.Tiles {
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00tiles\x00%s", lw.name, lw.modTime, lw.size, o.MapName, query.Encode())
+	if cached, ok := s.renders.Get(key); ok {
+		return cached.(*svg.Tiles)
+	}
+	tiles := svg.NewTiles(m, o)
+	s.renders.Add(key, tiles, tilesCacheSize*(len(m.LineDefs)+len(m.Things)))
+	return tiles
+}
+
+// serveRendered serves a map drawn by render with the options in the
+// query, from the render cache if it has been drawn before. variant tells
+// apart the different drawings of a map.
+func (s *mapServer) serveRendered(w http.ResponseWriter, r *http.Request, wadName string, mapName string, variant string, contentType string, render renderFunc) error {
+	lw, err := s.loadWAD(wadName)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%s\x00%s", lw.name, lw.modTime, lw.size, mapName, variant, query.Encode())
+	cached, ok := s.renders.Get(key)
+	if !ok {
+		o, err := renderOptsFromQuery(query)
+		if err != nil {
+			return err
+		}
+		m, err := lw.loadMap(r.Context(), mapName)
+		if err != nil {
+			return err
+		}
+		o.WadName = lw.name
+		o.MapName = mapName
+		o.Resources = lw.wad.Directory
+		out := &bytes.Buffer{}
+		if err := render(out, lw, m, o); err != nil {
+			return err
+		}
+		logging.Debug("Rendered map", "wad", lw.name, "map", mapName, "variant", variant, "bytes", out.Len())
+		cached = &rendered{contentType, fmt.Sprintf("\"%x\"", sha1.Sum(out.Bytes())), out.Bytes()}
+		s.renders.Add(key, cached, out.Len())
+	}
+	rendered := cached.(*rendered)
+	w.Header().Set("Content-Type", rendered.contentType)
+	w.Header().Set("ETag", rendered.etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", lw.modTime, bytes.NewReader(rendered.data))
+	return nil
+}
+
+// renderOptsFromQuery returns the rendering options given by the query
+// parameters, on top of the rendering flags given on the command line.
+func renderOptsFromQuery(query url.Values) (*svg.RenderOpts, error) {
+	o := &svg.RenderOpts{}
+	flags := pflag.NewFlagSet("query", pflag.ContinueOnError)
+	addRenderFlags(flags, o)
+	var err error
+	rootCmd.PersistentFlags().Visit(func(f *pflag.Flag) {
+		if flags.Lookup(f.Name) != nil && err == nil {
+			err = flags.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range query {
+		if flags.Lookup(name) == nil {
+			return nil, badRequest("unknown parameter %q", name)
+		}
+		for _, value := range values {
+			if err := flags.Set(name, value); err != nil {
+				return nil, badRequest("invalid %s %q: %v", name, value, err)
+			}
+		}
+	}
+	if err := validateRenderOpts(o); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if o.ImageWidth < 1 || o.ImageWidth > maxServedImageSize || o.ImageHeight < 1 || o.ImageHeight > maxServedImageSize {
+		return nil, badRequest("image_width and image_height must be between 1 and %d", maxServedImageSize)
+	}
+	return o, nil
+}
+
+// loadWAD returns the named WAD file in the served directory, reading it
+// again if it has changed since it was cached.
+func (s *mapServer) loadWAD(name string) (*loadedWAD, error) {
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") || !isWADFile(name) {
+		return nil, notFound("WAD file %q not found", name)
+	}
+	fileName := filepath.Join(s.dir, name)
+	info, err := os.Stat(fileName)
+	if os.IsNotExist(err) {
+		return nil, notFound("WAD file %q not found", name)
+	} else if err != nil {
+		return nil, err
+	}
+	if cached, ok := s.wads.Get(name); ok {
+		lw := cached.(*loadedWAD)
+		if lw.modTime.Equal(info.ModTime()) && lw.size == info.Size() {
+			return lw, nil
+		}
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := wad.NewWAD(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	logging.Debug("Loaded WAD file", "wad", name, "bytes", len(data))
+	lw := &loadedWAD{name: name, modTime: info.ModTime(), size: info.Size(), wad: w, maps: make(map[string]*wad.Map)}
+	s.wads.Add(name, lw, 1)
+	return lw, nil
+}
+
+// loadMap returns the named map, parsing it the first time it is asked for.
+func (lw *loadedWAD) loadMap(ctx context.Context, name string) (*wad.Map, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if m, ok := lw.maps[name]; ok {
+		return m, nil
+	}
+	found := false
+	for _, mapName := range lw.wad.Maps() {
+		found = found || mapName == name
+	}
+	if !found {
+		return nil, notFound("map %s not found in %s", name, lw.name)
+	}
+	m, err := lw.wad.LoadMap(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	lw.maps[name] = m
+	return m, nil
+}
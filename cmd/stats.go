@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats wad_file [map_name]",
+	Short: "Count the monsters, items, secrets and exits in each map of a WAD file",
+	Args:  cobra.RangeArgs(1, 2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if statsFormat != "table" && statsFormat != "csv" && statsFormat != "json" {
+			return fmt.Errorf("invalid --format %q, must be table, csv or json", statsFormat)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		maps := f.Maps()
+		if len(args) > 1 {
+			maps = []string{args[1]}
+		}
+		stats := make([]*analysis.Stats, 0, len(maps))
+		for _, name := range maps {
+			m, err := f.LoadMap(cmd.Context(), name)
+			if err != nil {
+				return err
+			}
+			stats = append(stats, analysis.MapStats(m, name))
+		}
+		switch statsFormat {
+		case "csv":
+			return writeStatsCSV(os.Stdout, stats)
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stats)
+		}
+		return writeStatsTable(os.Stdout, stats)
+	},
+}
+
+var statsFormat string
+
+func init() {
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "Output format (table, csv, json)")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func writeStatsTable(w io.Writer, stats []*analysis.Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for i, s := range stats {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s: %d secrets, %d exits, area %.0f, bounds (%d, %d) to (%d, %d)\n", s.Map, s.Secrets, s.Exits, s.Area, s.MinX, s.MinY, s.MaxX, s.MaxY)
+		fmt.Fprintln(tw, "SKILL\tMODE\tMONSTERS\tITEMS\tMONSTER HP\tAMMO DAMAGE\tAMMO/HP")
+		for _, c := range s.Counts {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%.2f\n", c.Skill, c.Mode, c.Monsters, c.Items, c.MonsterHP, c.AmmoDamage, c.AmmoHPRatio)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeStatsCSV(w io.Writer, stats []*analysis.Stats) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"map", "skill", "mode", "monsters", "items", "monster_hp", "ammo_damage", "ammo_hp_ratio", "secrets", "exits", "area", "min_x", "min_y", "max_x", "max_y"})
+	for _, s := range stats {
+		for _, c := range s.Counts {
+			cw.Write([]string{
+				s.Map, c.Skill.String(), c.Mode.String(),
+				strconv.Itoa(c.Monsters), strconv.Itoa(c.Items), strconv.Itoa(c.MonsterHP), strconv.Itoa(c.AmmoDamage),
+				strconv.FormatFloat(c.AmmoHPRatio, 'f', 2, 64),
+				strconv.Itoa(s.Secrets), strconv.Itoa(s.Exits), strconv.FormatFloat(s.Area, 'f', 0, 64),
+				strconv.Itoa(int(s.MinX)), strconv.Itoa(int(s.MinY)), strconv.Itoa(int(s.MaxX)), strconv.Itoa(int(s.MaxY)),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
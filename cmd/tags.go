@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags wad_file map_name",
+	Short: "Print which linedefs activate which sectors, as Graphviz DOT or JSON",
+	Args:  cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if tagsFormat != "dot" && tagsFormat != "json" {
+			return fmt.Errorf("invalid --format %q, must be dot or json", tagsFormat)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m, err := f.LoadMap(cmd.Context(), args[1])
+		if err != nil {
+			return err
+		}
+		if tagsFormat == "json" {
+			return writeActionsJSON(os.Stdout, m.Actions())
+		}
+		return writeActionsDOT(os.Stdout, m, args[1])
+	},
+}
+
+var tagsFormat string
+
+func init() {
+	tagsCmd.Flags().StringVar(&tagsFormat, "format", "dot", "Output format (dot, json)")
+	rootCmd.AddCommand(tagsCmd)
+}
+
+type actionJSON struct {
+	LineDef     int    `json:"linedef"`
+	Type        uint16 `json:"type"`
+	Trigger     string `json:"trigger,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Manual      bool   `json:"manual"`
+	Sectors     []int  `json:"sectors,omitempty"`
+	LineDefs    []int  `json:"linedefs,omitempty"`
+}
+
+func writeActionsJSON(w io.Writer, actions []wad.Action) error {
+	out := make([]actionJSON, 0, len(actions))
+	for _, a := range actions {
+		j := actionJSON{
+			LineDef:  a.LineDef,
+			Type:     a.Special.Type,
+			Trigger:  a.Special.Trigger.String(),
+			Key:      a.Special.Key.String(),
+			Manual:   a.Manual,
+			Sectors:  a.Sectors,
+			LineDefs: a.LineDefs,
+		}
+		if a.Special.Description != "" {
+			j.Category = a.Special.Category.String()
+			j.Description = a.Special.Description
+		}
+		out = append(out, j)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeActionsDOT(w io.Writer, m *wad.Map, mapName string) error {
+	fmt.Fprintf(w, "digraph %s {\n", strconv.Quote(mapName))
+	fmt.Fprintln(w, "  rankdir=LR;")
+	actions := m.Actions()
+	sectors := make(map[int]bool)
+	lineDefs := make(map[int]bool)
+	for _, a := range actions {
+		label := fmt.Sprintf("Linedef %d\nType %d", a.LineDef, a.Special.Type)
+		if a.Special.Description != "" {
+			label = fmt.Sprintf("Linedef %d\n%s", a.LineDef, a.Special)
+		}
+		fmt.Fprintf(w, "  L%d [shape=box, label=%s];\n", a.LineDef, strconv.Quote(label))
+		lineDefs[a.LineDef] = true
+		style := ""
+		if a.Manual {
+			style = " [style=dashed]"
+		}
+		for _, s := range a.Sectors {
+			if !sectors[s] {
+				sectors[s] = true
+				fmt.Fprintf(w, "  S%d [label=%s];\n", s, strconv.Quote(fmt.Sprintf("Sector %d\nTag %d", s, m.Sectors[s].TagNumber)))
+			}
+			fmt.Fprintf(w, "  L%d -> S%d%s;\n", a.LineDef, s, style)
+		}
+		for _, l := range a.LineDefs {
+			fmt.Fprintf(w, "  L%d -> L%d [style=dotted];\n", a.LineDef, l)
+		}
+	}
+	for _, a := range actions {
+		for _, l := range a.LineDefs {
+			if !lineDefs[l] {
+				lineDefs[l] = true
+				fmt.Fprintf(w, "  L%d [shape=box, label=%s];\n", l, strconv.Quote(fmt.Sprintf("Linedef %d", l)))
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
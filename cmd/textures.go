@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var texturesCmd = &cobra.Command{
+	Use:   "textures wad_file",
+	Short: "List the wall textures in a WAD file, optionally exporting them as PNGs",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		d := f.Directory
+		textures, err := d.Textures()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSIZE\tPATCHES\tLUMP")
+		for _, t := range textures {
+			fmt.Fprintf(tw, "%s\t%dx%d\t%d\t%s\n", t.Name, t.Width, t.Height, len(t.Patches), t.Lump)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if texturesExportDir == "" {
+			return nil
+		}
+		pal, err := d.Palette()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(texturesExportDir, 0755); err != nil {
+			return err
+		}
+		for i := range textures {
+			p, err := d.Composite(&textures[i])
+			if err != nil {
+				logging.Warn("Skipping texture", "texture", textures[i].Name, "error", err)
+				continue
+			}
+			fileName, err := joinLocal(texturesExportDir, pictureFileName(textures[i].Name))
+			if err != nil {
+				return err
+			}
+			if err := writePicture(fileName, p, pal); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var texturesExportDir string
+
+func init() {
+	texturesCmd.Flags().StringVar(&texturesExportDir, "export", "", "If set, write each texture as a PNG to this directory")
+	rootCmd.AddCommand(texturesCmd)
+}
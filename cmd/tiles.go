@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/macripps/wad2svg/svg"
+	"github.com/macripps/wad2svg/wad"
+	"github.com/spf13/cobra"
+)
+
+var tilesCmd = &cobra.Command{
+	Use:   "tiles wad_file map_name output_dir",
+	Short: "Write a map as a pyramid of XYZ tiles for Leaflet or OpenLayers, with a page to browse them",
+	Long: `Write a map as a pyramid of XYZ tiles for Leaflet or OpenLayers, with a page to browse them.
+
+Tiles are written to output_dir/{z}/{x}/{y}.png (or .svg), along with a
+TileJSON description in tiles.json and a Leaflet viewer in index.html. At
+zoom 0 a single tile covers the whole map.`,
+	Args: cobra.ExactArgs(3),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if tilesFormat != "png" && tilesFormat != "svg" {
+			return fmt.Errorf("invalid --format %q, must be png or svg", tilesFormat)
+		}
+		if tilesJobs < 1 {
+			return fmt.Errorf("invalid --jobs %d, must be at least 1", tilesJobs)
+		}
+		return rootCmd.PreRunE(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := wad.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m, err := f.LoadMap(cmd.Context(), args[1])
+		if err != nil {
+			return err
+		}
+		opts.WadName = filepath.Base(args[0])
+		opts.MapName = args[1]
+		opts.Resources = f.Directory
+		tiles := svg.NewTiles(m, opts)
+		if tilesMaxZoom >= 0 {
+			tiles.MaxZoom = tilesMaxZoom
+		}
+		outputDir := args[2]
+		if err := writeTilePyramid(tiles, outputDir, tilesFormat, tilesJobs); err != nil {
+			return err
+		}
+		name := opts.WadName + " - " + opts.MapName
+		tileURL := "{z}/{x}/{y}." + tilesFormat
+		err = writeFileAtomic(filepath.Join(outputDir, "tiles.json"), func(w io.Writer) error {
+			return writeTileJSON(w, tiles, name, tileURL)
+		})
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(filepath.Join(outputDir, "index.html"), func(w io.Writer) error {
+			return writeTileViewer(w, tiles, name, tileURL)
+		})
+	},
+}
+
+var tilesFormat string
+var tilesMaxZoom int
+var tilesJobs int
+
+func init() {
+	tilesCmd.Flags().StringVar(&tilesFormat, "format", "png", "Tile format (png, svg)")
+	tilesCmd.Flags().IntVar(&tilesMaxZoom, "max_zoom", -1, "Deepest zoom level to write, by default the first with two pixels per map unit")
+	tilesCmd.Flags().IntVar(&tilesJobs, "jobs", runtime.NumCPU(), "How many tiles to render at once")
+	rootCmd.AddCommand(tilesCmd)
+}
+
+// renderTile writes one tile in the given format, png or svg.
+func renderTile(w io.Writer, tiles *svg.Tiles, format string, z int, x int, y int) error {
+	if format == "svg" {
+		return tiles.RenderSVG(w, z, x, y)
+	}
+	return tiles.RenderPNG(w, z, x, y)
+}
+
+// writeTilePyramid writes every tile from zoom 0 to tiles.MaxZoom into
+// outputDir, several at once. Tiles with nothing on them are left out.
+func writeTilePyramid(tiles *svg.Tiles, outputDir string, format string, jobs int) error {
+	type tile struct{ z, x, y int }
+	work := make(chan tile)
+	errs := make(chan error, jobs)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range work {
+				dir := filepath.Join(outputDir, strconv.Itoa(t.z), strconv.Itoa(t.x))
+				err := os.MkdirAll(dir, 0755)
+				if err == nil {
+					err = writeFileAtomic(filepath.Join(dir, strconv.Itoa(t.y)+"."+format), func(w io.Writer) error {
+						return renderTile(w, tiles, format, t.z, t.x, t.y)
+					})
+				}
+				if err != nil {
+					errs <- err
+					// Drain the remaining work so that the sender is not
+					// left blocked.
+					for range work {
+					}
+					return
+				}
+			}
+		}()
+	}
+	for z := 0; z <= tiles.MaxZoom; z++ {
+		n := 1 << uint(z)
+		for x := 0; x < n; x++ {
+			for y := 0; y < n; y++ {
+				if !tiles.Empty(z, x, y) {
+					work <- tile{z, x, y}
+				}
+			}
+		}
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+type tileJSON struct {
+	TileJSON string     `json:"tilejson"`
+	Name     string     `json:"name"`
+	Scheme   string     `json:"scheme"`
+	Tiles    []string   `json:"tiles"`
+	MinZoom  int        `json:"minzoom"`
+	MaxZoom  int        `json:"maxzoom"`
+	TileSize int        `json:"tile_size"`
+	Origin   [2]float64 `json:"map_origin"`
+	Size     float64    `json:"map_size"`
+}
+
+// writeTileJSON describes the tiles in TileJSON. As the tiles are not of
+// the Earth, map_origin gives the map coordinates of the top left corner of
+// the zoom 0 tile, and map_size the map units it spans.
+func writeTileJSON(w io.Writer, tiles *svg.Tiles, name string, tileURL string) error {
+	minX, minY, side := tiles.Bounds()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tileJSON{
+		TileJSON: "2.2.0",
+		Name:     name,
+		Scheme:   "xyz",
+		Tiles:    []string{tileURL},
+		MaxZoom:  tiles.MaxZoom,
+		TileSize: svg.TileSize,
+		// The wad package flips y for SVG, so flip it back.
+		Origin: [2]float64{minX, -minY},
+		Size:   side,
+	})
+}
+
+var tileViewer = template.Must(template.New("viewer").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Name}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css">
+  <script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
+  <style>html, body, #map { height: 100%; margin: 0; background: #fff; }</style>
+</head>
+<body>
+  <div id="map"></div>
+  <script>
+    var bounds = [[-{{.TileSize}}, 0], [0, {{.TileSize}}]];
+    var map = L.map("map", {crs: L.CRS.Simple, minZoom: 0, maxZoom: {{.MaxZoom}} + 2});
+    L.tileLayer({{.TileURL}}, {tileSize: {{.TileSize}}, maxNativeZoom: {{.MaxZoom}}, noWrap: true, bounds: bounds}).addTo(map);
+    map.fitBounds(bounds);
+  </script>
+</body>
+</html>
+`))
+
+// writeTileViewer writes a page that shows the tiles with Leaflet.
+func writeTileViewer(w io.Writer, tiles *svg.Tiles, name string, tileURL string) error {
+	return tileViewer.Execute(w, struct {
+		Name     string
+		TileURL  string
+		TileSize int
+		MaxZoom  int
+	}{name, tileURL, svg.TileSize, tiles.MaxZoom})
+}
@@ -0,0 +1,161 @@
+// Package dxf writes a map as an AutoCAD R12 DXF drawing, which LibreCAD,
+// AutoCAD and most other CAD tools can open. Coordinates are Doom's map
+// units, with y increasing northwards.
+package dxf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// The layers linedefs and things are drawn on.
+const (
+	WALLS_LAYER     = "WALLS"
+	TWO_SIDED_LAYER = "TWO_SIDED"
+	SPECIALS_LAYER  = "SPECIALS"
+	THINGS_LAYER    = "THINGS"
+)
+
+// layers lists the layers with their AutoCAD colour numbers.
+var layers = []struct {
+	name   string
+	colour int
+}{
+	{WALLS_LAYER, 7},
+	{TWO_SIDED_LAYER, 8},
+	{SPECIALS_LAYER, 1},
+	{THINGS_LAYER, 3},
+}
+
+// defaultThingRadius is the size of the circle drawn for a thing of an
+// unknown type.
+const defaultThingRadius = 16
+
+// writer writes DXF group codes and values, remembering the first error.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (d *writer) group(code int, value interface{}) {
+	if d.err != nil {
+		return
+	}
+	if f, ok := value.(float64); ok {
+		value = fmt.Sprintf("%g", f)
+	}
+	_, d.err = fmt.Fprintf(d.w, "%3d\n%v\n", code, value)
+}
+
+func (d *writer) point(code int, x float64, y float64, z float64) {
+	d.group(code, x)
+	d.group(code+10, y)
+	d.group(code+20, z)
+}
+
+// Write writes the map as a DXF drawing. Linedefs become lines on the
+// WALLS, TWO_SIDED or SPECIALS layer, and things circles of their radius on
+// the THINGS layer. Each is raised to the floor height of the sector it is
+// in: for a linedef, the sector on its front side.
+func Write(w io.Writer, m *wad.Map) error {
+	d := &writer{w: bufio.NewWriter(w)}
+	minX, minY, maxX, maxY := bounds(m)
+	d.group(0, "SECTION")
+	d.group(2, "HEADER")
+	d.group(9, "$ACADVER")
+	d.group(1, "AC1009")
+	d.group(9, "$EXTMIN")
+	d.point(10, minX, minY, 0)
+	d.group(9, "$EXTMAX")
+	d.point(10, maxX, maxY, 0)
+	d.group(0, "ENDSEC")
+
+	d.group(0, "SECTION")
+	d.group(2, "TABLES")
+	d.group(0, "TABLE")
+	d.group(2, "LAYER")
+	d.group(70, len(layers))
+	for _, layer := range layers {
+		d.group(0, "LAYER")
+		d.group(2, layer.name)
+		d.group(70, 0)
+		d.group(62, layer.colour)
+		d.group(6, "CONTINUOUS")
+	}
+	d.group(0, "ENDTAB")
+	d.group(0, "ENDSEC")
+
+	d.group(0, "SECTION")
+	d.group(2, "ENTITIES")
+	for _, l := range m.LineDefs {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		right, left := m.LineDefSectors(l)
+		layer := WALLS_LAYER
+		if l.SpecialType != 0 {
+			layer = SPECIALS_LAYER
+		} else if left >= 0 {
+			layer = TWO_SIDED_LAYER
+		}
+		z := floorHeight(m, right)
+		start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+		d.group(0, "LINE")
+		d.group(8, layer)
+		// The wad package flips y for SVG, so flip it back.
+		d.point(10, float64(start.X), float64(-int(start.Y)), z)
+		d.point(11, float64(end.X), float64(-int(end.Y)), z)
+	}
+	for _, t := range m.Things {
+		radius := defaultThingRadius
+		if info, ok := t.Info(); ok {
+			radius = info.Radius
+		}
+		d.group(0, "CIRCLE")
+		d.group(8, THINGS_LAYER)
+		d.point(10, float64(t.XPosition), float64(-int(t.YPosition)), floorHeight(m, m.SectorAt(t.XPosition, t.YPosition)))
+		d.group(40, float64(radius))
+	}
+	d.group(0, "ENDSEC")
+	d.group(0, "EOF")
+	if d.err != nil {
+		return d.err
+	}
+	return d.w.Flush()
+}
+
+// floorHeight returns the floor height of a sector, or 0 for no sector.
+func floorHeight(m *wad.Map, sector int) float64 {
+	if sector < 0 || sector >= len(m.Sectors) {
+		return 0
+	}
+	return float64(m.Sectors[sector].FloorHeight)
+}
+
+// bounds returns the corners of the map in Doom's coordinates.
+func bounds(m *wad.Map) (float64, float64, float64, float64) {
+	if len(m.Vertexes) == 0 {
+		return 0, 0, 0, 0
+	}
+	minX, minY := float64(m.Vertexes[0].X), float64(-int(m.Vertexes[0].Y))
+	maxX, maxY := minX, minY
+	for _, v := range m.Vertexes {
+		x, y := float64(v.X), float64(-int(v.Y))
+		if x < minX {
+			minX = x
+		}
+		if y < minY {
+			minY = y
+		}
+		if x > maxX {
+			maxX = x
+		}
+		if y > maxY {
+			maxY = y
+		}
+	}
+	return minX, minY, maxX, maxY
+}
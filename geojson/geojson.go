@@ -0,0 +1,202 @@
+// Package geojson writes the geometry of a map as GeoJSON, for loading into
+// GIS tools and web mapping libraries. Coordinates are Doom's map units,
+// with y increasing northwards, rather than longitude and latitude.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// Geometry is a GeoJSON geometry.
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// Feature is a GeoJSON feature: a sector, linedef or thing. Its properties
+// are the Doom properties of what it describes, along with "kind" and
+// "index".
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// FeatureCollection is a whole map as GeoJSON.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Name     string    `json:"name,omitempty"`
+	Features []Feature `json:"features"`
+}
+
+type position [2]int
+
+// point returns a vertex in Doom's coordinates, undoing the flip of y that
+// the wad package makes for SVG.
+func point(v wad.Vertex) position {
+	return position{int(v.X), -int(v.Y)}
+}
+
+func ring(vertexes []wad.Vertex) []position {
+	r := make([]position, 0, len(vertexes)+1)
+	for _, v := range vertexes {
+		r = append(r, point(v))
+	}
+	// GeoJSON rings repeat their first position at the end.
+	return append(r, r[0])
+}
+
+// Collection returns the sectors, linedefs and things of the map as
+// features, in that order.
+func Collection(m *wad.Map, name string) *FeatureCollection {
+	c := &FeatureCollection{Type: "FeatureCollection", Name: name, Features: make([]Feature, 0)}
+	for i := range m.Sectors {
+		c.Features = append(c.Features, sectorFeature(m, i))
+	}
+	for i, l := range m.LineDefs {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		c.Features = append(c.Features, lineDefFeature(m, i, l))
+	}
+	for i, t := range m.Things {
+		c.Features = append(c.Features, thingFeature(i, t))
+	}
+	return c
+}
+
+// Write writes the map as a GeoJSON feature collection.
+func Write(w io.Writer, m *wad.Map, name string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Collection(m, name))
+}
+
+// sectorFeature describes a sector as a Polygon, or a MultiPolygon if it is
+// made of separate areas. A sector whose boundary does not close has no
+// geometry.
+func sectorFeature(m *wad.Map, i int) Feature {
+	s := m.Sectors[i]
+	polygons := make([][][]position, 0)
+	for _, p := range m.SectorPolygons(i) {
+		rings := [][]position{ring(p.Outer)}
+		for _, hole := range p.Holes {
+			rings = append(rings, ring(hole))
+		}
+		polygons = append(polygons, rings)
+	}
+	var geometry *Geometry
+	switch len(polygons) {
+	case 0:
+	case 1:
+		geometry = &Geometry{"Polygon", polygons[0]}
+	default:
+		geometry = &Geometry{"MultiPolygon", polygons}
+	}
+	return Feature{
+		Type:     "Feature",
+		ID:       fmt.Sprintf("sector-%d", i),
+		Geometry: geometry,
+		Properties: map[string]interface{}{
+			"kind":            "sector",
+			"index":           i,
+			"floor_height":    s.FloorHeight,
+			"ceiling_height":  s.CeilingHeight,
+			"floor_texture":   s.FloorTexture,
+			"ceiling_texture": s.CeilingTexture,
+			"light_level":     s.LightLevel,
+			"special":         s.SectorType,
+			"tag":             s.TagNumber,
+		},
+	}
+}
+
+// lineDefFlags names the bits of a linedef's flags.
+var lineDefFlags = []struct {
+	flag wad.LineDefFlag
+	name string
+}{
+	{wad.BLOCKS_MONSTERS_AND_PLAYERS, "impassable"},
+	{wad.BLOCKS_MONSTERS, "blocks_monsters"},
+	{wad.TWO_SIDED, "two_sided"},
+	{wad.UPPER_TEXTURE_UNPEGGED, "upper_unpegged"},
+	{wad.LOWER_TEXTURE_UNPEGGED, "lower_unpegged"},
+	{wad.SECRET, "secret"},
+	{wad.BLOCKS_SOUND, "blocks_sound"},
+	{wad.NEVER_SHOWN_ON_AUTOMAP, "not_on_automap"},
+	{wad.ALWAYS_SHOWN_ON_AUTOMAP, "on_automap"},
+}
+
+// lineDefFeature describes a linedef as a LineString from its start to its
+// end, with the properties of its sidedefs prefixed by front_ and back_.
+func lineDefFeature(m *wad.Map, i int, l wad.LineDef) Feature {
+	properties := map[string]interface{}{
+		"kind":         "linedef",
+		"index":        i,
+		"start_vertex": l.Start,
+		"end_vertex":   l.End,
+		"flags":        l.Flags,
+		"special":      l.SpecialType,
+		"tag":          l.SectorTag,
+	}
+	for _, f := range lineDefFlags {
+		properties[f.name] = l.Flags&uint16(f.flag) != 0
+	}
+	if special, ok := l.Special(); ok {
+		properties["special_description"] = special.String()
+		properties["special_category"] = special.Category.String()
+		properties["trigger"] = special.Trigger.String()
+		if special.Key != wad.NO_KEY {
+			properties["key"] = special.Key.String()
+		}
+	}
+	for _, side := range []struct {
+		prefix  string
+		sidedef uint16
+	}{{"front_", l.RightSideDef}, {"back_", l.LeftSideDef}} {
+		if int(side.sidedef) >= len(m.SideDefs) {
+			continue
+		}
+		sd := m.SideDefs[side.sidedef]
+		properties[side.prefix+"sidedef"] = side.sidedef
+		properties[side.prefix+"sector"] = sd.SectorNumber
+		properties[side.prefix+"x_offset"] = sd.XOffset
+		properties[side.prefix+"y_offset"] = sd.YOffset
+		properties[side.prefix+"upper_texture"] = sd.UpperTextureName
+		properties[side.prefix+"middle_texture"] = sd.MiddleTextureName
+		properties[side.prefix+"lower_texture"] = sd.LowerTextureName
+	}
+	return Feature{
+		Type:       "Feature",
+		ID:         fmt.Sprintf("linedef-%d", i),
+		Geometry:   &Geometry{"LineString", []position{point(m.Vertexes[l.Start]), point(m.Vertexes[l.End])}},
+		Properties: properties,
+	}
+}
+
+// thingFeature describes a thing as a Point, with its flags spelled out.
+func thingFeature(i int, t wad.Thing) Feature {
+	return Feature{
+		Type:     "Feature",
+		ID:       fmt.Sprintf("thing-%d", i),
+		Geometry: &Geometry{"Point", position{int(t.XPosition), -int(t.YPosition)}},
+		Properties: map[string]interface{}{
+			"kind":        "thing",
+			"index":       i,
+			"type":        t.ThingType,
+			"name":        t.Name(),
+			"angle":       t.Angle,
+			"flags":       t.Flags,
+			"easy":        t.Flags&1 != 0,
+			"medium":      t.Flags&2 != 0,
+			"hard":        t.Flags&4 != 0,
+			"ambush":      t.Flags&8 != 0,
+			"multiplayer": t.Flags&16 != 0,
+		},
+	}
+}
@@ -4,5 +4,6 @@ go 1.12
 
 require (
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
-	github.com/spf13/cobra v1.5.0 // indirect
+	github.com/spf13/cobra v1.5.0
+	github.com/spf13/pflag v1.0.5
 )
@@ -0,0 +1,99 @@
+// Package logging writes levelled diagnostics as key=value pairs, so that
+// they can be filtered by --verbose and --quiet and read by other tools.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level is how important a message is.
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Logger writes messages at or above its level. It is safe to use from
+// several goroutines.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+}
+
+// New returns a logger writing messages at or above level to out.
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{out: out, level: level}
+}
+
+// SetLevel changes the least important level that is written.
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// log writes one line: the level, the message and then the key value pairs.
+func (l *Logger) log(level Level, msg string, keyValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level {
+		return
+	}
+	line := strings.Builder{}
+	line.WriteString("level=" + level.String() + " msg=" + quote(msg))
+	for i := 0; i < len(keyValues); i += 2 {
+		key := fmt.Sprint(keyValues[i])
+		value := "MISSING"
+		if i+1 < len(keyValues) {
+			value = fmt.Sprint(keyValues[i+1])
+		}
+		line.WriteString(" " + key + "=" + quote(value))
+	}
+	line.WriteString("\n")
+	io.WriteString(l.out, line.String())
+}
+
+func (l *Logger) Debug(msg string, keyValues ...interface{}) { l.log(DEBUG, msg, keyValues) }
+func (l *Logger) Info(msg string, keyValues ...interface{})  { l.log(INFO, msg, keyValues) }
+func (l *Logger) Warn(msg string, keyValues ...interface{})  { l.log(WARN, msg, keyValues) }
+func (l *Logger) Error(msg string, keyValues ...interface{}) { l.log(ERROR, msg, keyValues) }
+
+var std = New(os.Stderr, INFO)
+
+// Default returns the logger the package level functions write to, which
+// writes informational messages and above to stderr.
+func Default() *Logger {
+	return std
+}
+
+// SetLevel changes the level of the default logger.
+func SetLevel(level Level) {
+	std.SetLevel(level)
+}
+
+func Debug(msg string, keyValues ...interface{}) { std.log(DEBUG, msg, keyValues) }
+func Info(msg string, keyValues ...interface{})  { std.log(INFO, msg, keyValues) }
+func Warn(msg string, keyValues ...interface{})  { std.log(WARN, msg, keyValues) }
+func Error(msg string, keyValues ...interface{}) { std.log(ERROR, msg, keyValues) }
@@ -0,0 +1,237 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image/png"
+	"io"
+	"math"
+)
+
+// glTF constants for component types, buffer view targets and samplers.
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+	gltfNearest      = 9728
+	gltfRepeat       = 10497
+)
+
+type gltfDocument struct {
+	Asset       map[string]string `json:"asset"`
+	Scene       int               `json:"scene"`
+	Scenes      []gltfScene       `json:"scenes"`
+	Nodes       []gltfNode        `json:"nodes"`
+	Meshes      []gltfMesh        `json:"meshes"`
+	Materials   []gltfMaterial    `json:"materials"`
+	Textures    []gltfTexture     `json:"textures,omitempty"`
+	Images      []gltfImage       `json:"images,omitempty"`
+	Samplers    []gltfSampler     `json:"samplers,omitempty"`
+	Accessors   []gltfAccessor    `json:"accessors"`
+	BufferViews []gltfBufferView  `json:"bufferViews"`
+	Buffers     []gltfBuffer      `json:"buffers"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Mesh int `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+}
+
+type gltfMaterial struct {
+	Name        string  `json:"name"`
+	PBR         gltfPBR `json:"pbrMetallicRoughness"`
+	AlphaMode   string  `json:"alphaMode,omitempty"`
+	AlphaCutoff float64 `json:"alphaCutoff,omitempty"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor  [4]float64        `json:"baseColorFactor"`
+	BaseColorTexture *gltfTextureIndex `json:"baseColorTexture,omitempty"`
+	MetallicFactor   float64           `json:"metallicFactor"`
+	RoughnessFactor  float64           `json:"roughnessFactor"`
+}
+
+type gltfTextureIndex struct {
+	Index int `json:"index"`
+}
+
+type gltfTexture struct {
+	Sampler int `json:"sampler"`
+	Source  int `json:"source"`
+}
+
+type gltfImage struct {
+	Name       string `json:"name"`
+	BufferView int    `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// gltfBuilder collects the JSON description of a model and the binary
+// buffer it refers to.
+type gltfBuilder struct {
+	doc gltfDocument
+	bin bytes.Buffer
+}
+
+// addView appends data to the buffer, keeping views 4 byte aligned, and
+// returns the index of its buffer view.
+func (g *gltfBuilder) addView(data []byte, target int) int {
+	for g.bin.Len()%4 != 0 {
+		g.bin.WriteByte(0)
+	}
+	g.doc.BufferViews = append(g.doc.BufferViews, gltfBufferView{ByteOffset: g.bin.Len(), ByteLength: len(data), Target: target})
+	g.bin.Write(data)
+	return len(g.doc.BufferViews) - 1
+}
+
+func (g *gltfBuilder) addAccessor(a gltfAccessor) int {
+	g.doc.Accessors = append(g.doc.Accessors, a)
+	return len(g.doc.Accessors) - 1
+}
+
+func float32Bytes(values ...float32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+// WriteGLB writes the mesh as binary glTF, with its textures embedded, as a
+// single mesh with a primitive for each material.
+func (ms *Mesh) WriteGLB(w io.Writer) error {
+	g := &gltfBuilder{}
+	g.doc.Asset = map[string]string{"version": "2.0", "generator": "wad2svg"}
+	g.doc.Scenes = []gltfScene{{Nodes: []int{0}}}
+	g.doc.Nodes = []gltfNode{{Mesh: 0}}
+	mesh := gltfMesh{Primitives: make([]gltfPrimitive, 0, len(ms.Surfaces))}
+	g.doc.Materials = make([]gltfMaterial, 0, len(ms.Surfaces))
+	for _, s := range ms.Surfaces {
+		material, err := g.addMaterial(s.Material)
+		if err != nil {
+			return err
+		}
+		positions := make([]byte, 0, 12*len(s.Vertices))
+		normals := make([]byte, 0, 12*len(s.Vertices))
+		uvs := make([]byte, 0, 8*len(s.Vertices))
+		for _, v := range s.Vertices {
+			positions = append(positions, float32Bytes(v.Position[:]...)...)
+			normals = append(normals, float32Bytes(v.Normal[:]...)...)
+			uvs = append(uvs, float32Bytes(v.UV[:]...)...)
+		}
+		indices := make([]byte, 4*len(s.Indices))
+		for i, index := range s.Indices {
+			binary.LittleEndian.PutUint32(indices[4*i:], index)
+		}
+		min, max := s.bounds()
+		n := len(s.Vertices)
+		mesh.Primitives = append(mesh.Primitives, gltfPrimitive{
+			Attributes: map[string]int{
+				"POSITION":   g.addAccessor(gltfAccessor{BufferView: g.addView(positions, gltfArrayBuffer), ComponentType: gltfFloat, Count: n, Type: "VEC3", Min: min[:], Max: max[:]}),
+				"NORMAL":     g.addAccessor(gltfAccessor{BufferView: g.addView(normals, gltfArrayBuffer), ComponentType: gltfFloat, Count: n, Type: "VEC3"}),
+				"TEXCOORD_0": g.addAccessor(gltfAccessor{BufferView: g.addView(uvs, gltfArrayBuffer), ComponentType: gltfFloat, Count: n, Type: "VEC2"}),
+			},
+			Indices:  g.addAccessor(gltfAccessor{BufferView: g.addView(indices, gltfElementArray), ComponentType: gltfUnsignedInt, Count: len(s.Indices), Type: "SCALAR"}),
+			Material: material,
+		})
+	}
+	g.doc.Meshes = []gltfMesh{mesh}
+	for g.bin.Len()%4 != 0 {
+		g.bin.WriteByte(0)
+	}
+	g.doc.Buffers = []gltfBuffer{{ByteLength: g.bin.Len()}}
+	doc, err := json.Marshal(g.doc)
+	if err != nil {
+		return err
+	}
+	// The JSON chunk is padded with spaces, and the binary one with zeros.
+	for len(doc)%4 != 0 {
+		doc = append(doc, ' ')
+	}
+	header := make([]byte, 20)
+	copy(header[0:4], "glTF")
+	binary.LittleEndian.PutUint32(header[4:], 2)
+	binary.LittleEndian.PutUint32(header[8:], uint32(12+8+len(doc)+8+g.bin.Len()))
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(doc)))
+	copy(header[16:20], "JSON")
+	chunk := make([]byte, 8)
+	binary.LittleEndian.PutUint32(chunk[0:], uint32(g.bin.Len()))
+	copy(chunk[4:8], "BIN\x00")
+	for _, b := range [][]byte{header, doc, chunk, g.bin.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMaterial adds a material, with its image embedded as a PNG if it has
+// one, and returns its index.
+func (g *gltfBuilder) addMaterial(m *Material) (int, error) {
+	material := gltfMaterial{
+		Name: m.Name,
+		PBR:  gltfPBR{BaseColorFactor: [4]float64{0.5, 0.5, 0.5, 1}, RoughnessFactor: 1},
+	}
+	if m.Image != nil {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, m.Image); err != nil {
+			return 0, err
+		}
+		if len(g.doc.Samplers) == 0 {
+			// Keep the pixels sharp, as in the game.
+			g.doc.Samplers = []gltfSampler{{gltfNearest, gltfNearest, gltfRepeat, gltfRepeat}}
+		}
+		g.doc.Images = append(g.doc.Images, gltfImage{Name: m.Name, BufferView: g.addView(buf.Bytes(), 0), MimeType: "image/png"})
+		g.doc.Textures = append(g.doc.Textures, gltfTexture{Sampler: 0, Source: len(g.doc.Images) - 1})
+		material.PBR.BaseColorFactor = [4]float64{1, 1, 1, 1}
+		material.PBR.BaseColorTexture = &gltfTextureIndex{len(g.doc.Textures) - 1}
+		if hasTransparency(m) {
+			material.AlphaMode = "MASK"
+			material.AlphaCutoff = 0.5
+		}
+	}
+	g.doc.Materials = append(g.doc.Materials, material)
+	return len(g.doc.Materials) - 1, nil
+}
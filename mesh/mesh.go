@@ -0,0 +1,318 @@
+// Package mesh builds a 3D model of a map, with floors and ceilings at their
+// heights and walls between them, and writes it as Wavefront OBJ or binary
+// glTF. The model is y-up, in Doom's map units, with Doom's north along -z.
+package mesh
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+)
+
+// Vertex is a corner of a triangle. UV is measured in texture widths and
+// heights from the top left corner of the texture.
+type Vertex struct {
+	Position [3]float32
+	Normal   [3]float32
+	UV       [2]float32
+}
+
+// Material is a flat or wall texture, named "flat_" or "wall_" followed by
+// the texture's name, or "untextured" for walls missing one. Image is nil
+// when the model is untextured or the texture cannot be found.
+type Material struct {
+	Name    string
+	Texture string
+	Image   *image.NRGBA
+}
+
+// Surface is the triangles drawn with one material.
+type Surface struct {
+	Material *Material
+	Vertices []Vertex
+	Indices  []uint32
+}
+
+// Mesh is a model of a map, as one surface for each material.
+type Mesh struct {
+	Surfaces []*Surface
+}
+
+// defaultTextureSize is the size assumed for textures that have not been
+// loaded, which only matters for texture coordinates.
+const defaultTextureSize = 64
+
+const untextured = "untextured"
+
+type builder struct {
+	m        *wad.Map
+	d        *wad.Directory
+	pal      wad.Palette
+	textures map[string]*wad.Texture
+	surfaces map[string]*Surface
+}
+
+// Build makes a model of the map. If d is not nil, flats and wall textures
+// are decoded from it for the materials. Without a palette to decode them
+// with, the model is left untextured.
+func Build(m *wad.Map, d *wad.Directory) (*Mesh, error) {
+	b := &builder{m: m, d: d, surfaces: make(map[string]*Surface)}
+	if d != nil {
+		pal, err := d.Palette()
+		if err != nil {
+			logging.Warn("Unable to read PLAYPAL, leaving the model untextured", "error", err)
+			b.d = nil
+		}
+		b.pal = pal
+	}
+	if b.d != nil {
+		b.textures = make(map[string]*wad.Texture)
+		textures, err := b.d.Textures()
+		if err != nil {
+			logging.Warn("Unable to read wall textures", "error", err)
+		}
+		for i := range textures {
+			// Doom uses the first definition of a name.
+			if _, ok := b.textures[textures[i].Name]; !ok {
+				b.textures[textures[i].Name] = &textures[i]
+			}
+		}
+	}
+	for i := range m.Sectors {
+		b.addSector(i)
+	}
+	for _, l := range m.LineDefs {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		b.addSide(l)
+		b.addSide(l.Flip())
+	}
+	names := make([]string, 0, len(b.surfaces))
+	for name := range b.surfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mesh := &Mesh{Surfaces: make([]*Surface, 0, len(names))}
+	for _, name := range names {
+		if s := b.surfaces[name]; len(s.Indices) > 0 {
+			mesh.Surfaces = append(mesh.Surfaces, s)
+		}
+	}
+	return mesh, nil
+}
+
+// surface returns the surface for a flat, or a wall texture if wall is true,
+// loading its image the first time it is asked for.
+func (b *builder) surface(texture string, wall bool) *Surface {
+	texture = strings.ToUpper(texture)
+	name := "flat_" + texture
+	if wall {
+		name = "wall_" + texture
+		if texture == "" || texture == "-" {
+			name, texture = untextured, ""
+		}
+	}
+	if s, ok := b.surfaces[name]; ok {
+		return s
+	}
+	s := &Surface{Material: &Material{Name: name, Texture: texture}}
+	b.surfaces[name] = s
+	if b.d == nil || texture == "" {
+		return s
+	}
+	if wall {
+		t, ok := b.textures[texture]
+		if !ok {
+			logging.Warn("No wall texture found", "texture", texture)
+			return s
+		}
+		p, err := b.d.Composite(t)
+		if err != nil {
+			logging.Warn("Unable to compose wall texture", "texture", texture, "error", err)
+			return s
+		}
+		s.Material.Image = p.Image(b.pal)
+		return s
+	}
+	l := b.d.Flat(texture)
+	if l == nil {
+		logging.Warn("No flat found", "flat", texture)
+		return s
+	}
+	f, err := wad.DecodeFlat(b.d.ReadLump(l))
+	if err != nil {
+		logging.Warn("Unable to decode flat", "flat", texture, "error", err)
+		return s
+	}
+	s.Material.Image = f.Image(b.pal)
+	return s
+}
+
+// wallSize returns the size of a wall texture.
+func (b *builder) wallSize(texture string) (float64, float64) {
+	if t, ok := b.textures[strings.ToUpper(texture)]; ok && t.Width > 0 && t.Height > 0 {
+		return float64(t.Width), float64(t.Height)
+	}
+	return defaultTextureSize, defaultTextureSize
+}
+
+// addSector triangulates the sector's floor, facing up, and its ceiling,
+// facing down. Flats are tiled from the map's origin, as in the game.
+func (b *builder) addSector(i int) {
+	s := b.m.Sectors[i]
+	floor := b.surface(s.FloorTexture, false)
+	ceiling := b.surface(s.CeilingTexture, false)
+	for _, p := range b.m.SectorPolygons(i) {
+		vertexes := append([]wad.Vertex{}, p.Outer...)
+		outer := doomPoints(p.Outer)
+		holes := make([][]point, len(p.Holes))
+		for h, hole := range p.Holes {
+			vertexes = append(vertexes, hole...)
+			holes[h] = doomPoints(hole)
+		}
+		triangles := triangulate(outer, holes)
+		for _, surface := range []struct {
+			s      *Surface
+			height int16
+			normal float32
+		}{{floor, s.FloorHeight, 1}, {ceiling, s.CeilingHeight, -1}} {
+			base := uint32(len(surface.s.Vertices))
+			for _, v := range vertexes {
+				surface.s.Vertices = append(surface.s.Vertices, Vertex{
+					Position: [3]float32{float32(v.X), float32(surface.height), float32(v.Y)},
+					Normal:   [3]float32{0, surface.normal, 0},
+					UV:       [2]float32{float32(v.X) / 64, float32(v.Y) / 64},
+				})
+			}
+			for _, t := range triangles {
+				if surface.normal > 0 {
+					surface.s.Indices = append(surface.s.Indices, base+uint32(t[0]), base+uint32(t[1]), base+uint32(t[2]))
+				} else {
+					surface.s.Indices = append(surface.s.Indices, base+uint32(t[2]), base+uint32(t[1]), base+uint32(t[0]))
+				}
+			}
+		}
+	}
+}
+
+// doomPoints returns a ring in Doom's coordinates, undoing the flip of y
+// that the wad package makes for SVG.
+func doomPoints(ring []wad.Vertex) []point {
+	points := make([]point, len(ring))
+	for i, v := range ring {
+		points[i] = point{float64(v.X), -float64(v.Y)}
+	}
+	return points
+}
+
+// addSide adds the walls seen from the right side of l: the middle of a one
+// sided linedef, or else the lower and upper walls where the sector behind
+// is higher or lower, and any middle texture across the opening.
+func (b *builder) addSide(l wad.LineDef) {
+	if int(l.RightSideDef) >= len(b.m.SideDefs) {
+		return
+	}
+	sd := b.m.SideDefs[l.RightSideDef]
+	front, back := b.m.LineDefSectors(l)
+	if front < 0 || front >= len(b.m.Sectors) {
+		return
+	}
+	f := b.m.Sectors[front]
+	floor, ceiling := float64(f.FloorHeight), float64(f.CeilingHeight)
+	lowerUnpegged := l.Flags&uint16(wad.LOWER_TEXTURE_UNPEGGED) != 0
+	upperUnpegged := l.Flags&uint16(wad.UPPER_TEXTURE_UNPEGGED) != 0
+	if back < 0 || back >= len(b.m.Sectors) {
+		_, h := b.wallSize(sd.MiddleTextureName)
+		top := ceiling
+		if lowerUnpegged {
+			top = floor + h
+		}
+		b.addWall(l, sd, sd.MiddleTextureName, floor, ceiling, top)
+		return
+	}
+	bs := b.m.Sectors[back]
+	backFloor, backCeiling := float64(bs.FloorHeight), float64(bs.CeilingHeight)
+	if backFloor > floor {
+		top := backFloor
+		if lowerUnpegged {
+			top = ceiling
+		}
+		b.addWall(l, sd, sd.LowerTextureName, floor, backFloor, top)
+	}
+	// Doom draws no upper wall between two skies.
+	if backCeiling < ceiling && !(strings.EqualFold(f.CeilingTexture, "F_SKY1") && strings.EqualFold(bs.CeilingTexture, "F_SKY1")) {
+		_, h := b.wallSize(sd.UpperTextureName)
+		top := backCeiling + h
+		if upperUnpegged {
+			top = ceiling
+		}
+		b.addWall(l, sd, sd.UpperTextureName, backCeiling, ceiling, top)
+	}
+	if sd.MiddleTextureName != "" && sd.MiddleTextureName != "-" {
+		// A middle texture on a two sided linedef is drawn once, not tiled,
+		// and cut off by the opening.
+		bottom, top := math.Max(floor, backFloor), math.Min(ceiling, backCeiling)
+		_, h := b.wallSize(sd.MiddleTextureName)
+		textureTop := top
+		if lowerUnpegged {
+			textureTop = bottom + h
+		}
+		textureTop += float64(sd.YOffset)
+		b.addWall(l, sd, sd.MiddleTextureName, math.Max(bottom, textureTop-h), math.Min(top, textureTop), textureTop-float64(sd.YOffset))
+	}
+}
+
+// addWall adds a wall from bottom to top along the right side of l, with
+// the top of its texture at textureTop before the sidedef's offsets.
+func (b *builder) addWall(l wad.LineDef, sd wad.SideDef, texture string, bottom float64, top float64, textureTop float64) {
+	if top <= bottom {
+		return
+	}
+	start, end := b.m.Vertexes[l.Start], b.m.Vertexes[l.End]
+	dx, dz := float64(end.X)-float64(start.X), float64(end.Y)-float64(start.Y)
+	length := math.Hypot(dx, dz)
+	if length == 0 {
+		return
+	}
+	s := b.surface(texture, true)
+	w, h := b.wallSize(texture)
+	normal := [3]float32{float32(-dz / length), 0, float32(dx / length)}
+	base := uint32(len(s.Vertices))
+	for _, c := range []struct {
+		v      wad.Vertex
+		along  float64
+		height float64
+	}{{start, 0, bottom}, {end, length, bottom}, {end, length, top}, {start, 0, top}} {
+		s.Vertices = append(s.Vertices, Vertex{
+			Position: [3]float32{float32(c.v.X), float32(c.height), float32(c.v.Y)},
+			Normal:   normal,
+			UV: [2]float32{
+				float32((c.along + float64(sd.XOffset)) / w),
+				float32((textureTop - c.height + float64(sd.YOffset)) / h),
+			},
+		})
+	}
+	s.Indices = append(s.Indices, base, base+1, base+2, base, base+2, base+3)
+}
+
+// bounds returns the smallest and largest coordinates of the surface's
+// vertexes.
+func (s *Surface) bounds() ([3]float32, [3]float32) {
+	min, max := s.Vertices[0].Position, s.Vertices[0].Position
+	for _, v := range s.Vertices {
+		for i, c := range v.Position {
+			if c < min[i] {
+				min[i] = c
+			}
+			if c > max[i] {
+				max[i] = c
+			}
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,75 @@
+package mesh
+
+import (
+	"bufio"
+	"fmt"
+	"image/png"
+	"io"
+)
+
+// WriteOBJ writes the mesh as a Wavefront OBJ model using the materials in
+// the MTL file mtlName, with a group for each material.
+func (ms *Mesh) WriteOBJ(w io.Writer, mtlName string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mtllib %s\n", mtlName)
+	base := 1
+	for _, s := range ms.Surfaces {
+		fmt.Fprintf(bw, "g %s\nusemtl %s\n", s.Material.Name, s.Material.Name)
+		for _, v := range s.Vertices {
+			fmt.Fprintf(bw, "v %g %g %g\n", v.Position[0], v.Position[1], v.Position[2])
+		}
+		for _, v := range s.Vertices {
+			// OBJ measures texture coordinates from the bottom left.
+			fmt.Fprintf(bw, "vt %g %g\n", v.UV[0], 1-v.UV[1])
+		}
+		for _, v := range s.Vertices {
+			fmt.Fprintf(bw, "vn %g %g %g\n", v.Normal[0], v.Normal[1], v.Normal[2])
+		}
+		for i := 0; i+2 < len(s.Indices); i += 3 {
+			a, b, c := base+int(s.Indices[i]), base+int(s.Indices[i+1]), base+int(s.Indices[i+2])
+			fmt.Fprintf(bw, "f %d/%d/%d %d/%d/%d %d/%d/%d\n", a, a, a, b, b, b, c, c, c)
+		}
+		base += len(s.Vertices)
+	}
+	return bw.Flush()
+}
+
+// WriteMTL writes the materials of the mesh. textureFile returns the name of
+// the image file for a material with an image, which the caller writes with
+// Material.WritePNG. Materials without one are plain grey.
+func (ms *Mesh) WriteMTL(w io.Writer, textureFile func(m *Material) string) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range ms.Surfaces {
+		m := s.Material
+		fmt.Fprintf(bw, "newmtl %s\n", m.Name)
+		if m.Image == nil {
+			fmt.Fprintln(bw, "Kd 0.5 0.5 0.5")
+			continue
+		}
+		file := textureFile(m)
+		fmt.Fprintf(bw, "Kd 1 1 1\nmap_Kd %s\n", file)
+		if hasTransparency(m) {
+			fmt.Fprintf(bw, "map_d %s\n", file)
+		}
+	}
+	return bw.Flush()
+}
+
+// hasTransparency reports whether the material's image has any undrawn
+// pixels, as the middle textures of two sided linedefs often do.
+func hasTransparency(m *Material) bool {
+	if m.Image == nil {
+		return false
+	}
+	for i := 3; i < len(m.Image.Pix); i += 4 {
+		if m.Image.Pix[i] != 0xff {
+			return true
+		}
+	}
+	return false
+}
+
+// WritePNG writes the material's image as a PNG.
+func (m *Material) WritePNG(w io.Writer) error {
+	return png.Encode(w, m.Image)
+}
@@ -0,0 +1,194 @@
+package mesh
+
+import (
+	"math"
+	"sort"
+)
+
+type point struct{ x, y float64 }
+
+func cross(o point, a point, b point) float64 {
+	return (a.x-o.x)*(b.y-o.y) - (a.y-o.y)*(b.x-o.x)
+}
+
+// triangulate splits a polygon into triangles by ear clipping, returning
+// indices into the points of the outer ring followed by those of each hole.
+// The outer ring must run anticlockwise and the holes clockwise.
+func triangulate(outer []point, holes [][]point) [][3]int {
+	points := append([]point{}, outer...)
+	ring := make([]int, len(outer))
+	for i := range ring {
+		ring[i] = i
+	}
+	offsets := make([]int, len(holes))
+	for h, hole := range holes {
+		offsets[h] = len(points)
+		points = append(points, hole...)
+	}
+	// Join the holes to the outer ring, rightmost first, so that each bridge
+	// can be checked against the holes still to be joined.
+	order := make([]int, len(holes))
+	for h := range order {
+		order[h] = h
+	}
+	rightmost := func(h int) int {
+		best := 0
+		for i, p := range holes[h] {
+			if p.x > holes[h][best].x {
+				best = i
+			}
+		}
+		return best
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return holes[order[a]][rightmost(order[a])].x > holes[order[b]][rightmost(order[b])].x
+	})
+	for n, h := range order {
+		ring = bridgeHole(points, ring, holes[h], offsets[h], rightmost(h), holes, order[n+1:], offsets)
+	}
+	return clipEars(points, ring)
+}
+
+// bridgeHole joins a hole to the ring with a pair of coincident edges from
+// the hole's vertex at start to the nearest ring vertex in sight of it.
+func bridgeHole(points []point, ring []int, hole []point, offset int, start int, holes [][]point, remaining []int, offsets []int) []int {
+	m := hole[start]
+	candidates := make([]int, len(ring))
+	for i := range candidates {
+		candidates[i] = i
+	}
+	distance := func(i int) float64 {
+		p := points[ring[i]]
+		return (p.x-m.x)*(p.x-m.x) + (p.y-m.y)*(p.y-m.y)
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return distance(candidates[a]) < distance(candidates[b]) })
+	bridge := candidates[0]
+	for _, i := range candidates {
+		if bridgeVisible(points, ring, m, points[ring[i]], hole, holes, remaining) {
+			bridge = i
+			break
+		}
+	}
+	joined := make([]int, 0, len(ring)+len(hole)+2)
+	joined = append(joined, ring[:bridge+1]...)
+	for i := 0; i <= len(hole); i++ {
+		joined = append(joined, offset+(start+i)%len(hole))
+	}
+	joined = append(joined, ring[bridge])
+	return append(joined, ring[bridge+1:]...)
+}
+
+// bridgeVisible reports whether the segment from a to b crosses no edge of
+// the ring, of the hole being joined, or of the holes still to be joined,
+// passes through none of their vertexes, and runs inside the ring.
+func bridgeVisible(points []point, ring []int, a point, b point, hole []point, holes [][]point, remaining []int) bool {
+	for i := range ring {
+		if segmentsCross(a, b, points[ring[i]], points[ring[(i+1)%len(ring)]]) || onSegment(a, b, points[ring[i]]) {
+			return false
+		}
+	}
+	for _, h := range append([][]point{hole}, holesOf(holes, remaining)...) {
+		for i := range h {
+			if segmentsCross(a, b, h[i], h[(i+1)%len(h)]) || onSegment(a, b, h[i]) {
+				return false
+			}
+		}
+	}
+	mid := point{(a.x + b.x) / 2, (a.y + b.y) / 2}
+	inside := false
+	for i := range ring {
+		p, q := points[ring[i]], points[ring[(i+1)%len(ring)]]
+		if (p.y > mid.y) != (q.y > mid.y) && mid.x < (q.x-p.x)*(mid.y-p.y)/(q.y-p.y)+p.x {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func holesOf(holes [][]point, indices []int) [][]point {
+	result := make([][]point, len(indices))
+	for i, h := range indices {
+		result[i] = holes[h]
+	}
+	return result
+}
+
+// segmentsCross reports whether two segments cross at a point other than
+// an end of either.
+func segmentsCross(a point, b point, c point, d point) bool {
+	if a == c || a == d || b == c || b == d {
+		return false
+	}
+	d1, d2 := cross(a, b, c), cross(a, b, d)
+	d3, d4 := cross(c, d, a), cross(c, d, b)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// onSegment reports whether p lies on the segment from a to b, other than at
+// either end.
+func onSegment(a point, b point, p point) bool {
+	if p == a || p == b || cross(a, b, p) != 0 {
+		return false
+	}
+	return math.Min(a.x, b.x) <= p.x && p.x <= math.Max(a.x, b.x) && math.Min(a.y, b.y) <= p.y && p.y <= math.Max(a.y, b.y)
+}
+
+// clipEars triangulates a simple anticlockwise ring of point indices.
+func clipEars(points []point, ring []int) [][3]int {
+	triangles := make([][3]int, 0, len(ring))
+	for len(ring) > 3 {
+		clipped := false
+		for i := range ring {
+			prev, cur, next := ring[(i+len(ring)-1)%len(ring)], ring[i], ring[(i+1)%len(ring)]
+			turn := cross(points[prev], points[cur], points[next])
+			if turn == 0 {
+				// A vertex in a straight line, or at the end of a bridge, adds
+				// nothing but a zero area triangle.
+				ring = append(ring[:i], ring[i+1:]...)
+				clipped = true
+				break
+			}
+			if turn < 0 || !isEar(points, ring, prev, cur, next) {
+				continue
+			}
+			triangles = append(triangles, [3]int{prev, cur, next})
+			ring = append(ring[:i], ring[i+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			// The ring is not simple. Clip the least bad vertex so that the
+			// rest of it is still filled.
+			best, bestTurn := 0, math.Inf(-1)
+			for i := range ring {
+				turn := cross(points[ring[(i+len(ring)-1)%len(ring)]], points[ring[i]], points[ring[(i+1)%len(ring)]])
+				if turn > bestTurn {
+					best, bestTurn = i, turn
+				}
+			}
+			if bestTurn > 0 {
+				triangles = append(triangles, [3]int{ring[(best+len(ring)-1)%len(ring)], ring[best], ring[(best+1)%len(ring)]})
+			}
+			ring = append(ring[:best], ring[best+1:]...)
+		}
+	}
+	if len(ring) == 3 && cross(points[ring[0]], points[ring[1]], points[ring[2]]) > 0 {
+		triangles = append(triangles, [3]int{ring[0], ring[1], ring[2]})
+	}
+	return triangles
+}
+
+// isEar reports whether no other vertex of the ring lies in the triangle.
+func isEar(points []point, ring []int, a int, b int, c int) bool {
+	pa, pb, pc := points[a], points[b], points[c]
+	for _, i := range ring {
+		p := points[i]
+		if p == pa || p == pb || p == pc {
+			continue
+		}
+		if cross(pa, pb, p) >= 0 && cross(pb, pc, p) >= 0 && cross(pc, pa, p) >= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+)
+
+// square returns an anticlockwise square with its lower left corner at x, y.
+func square(x float64, y float64, side float64) []point {
+	return []point{{x, y}, {x + side, y}, {x + side, y + side}, {x, y + side}}
+}
+
+// reversed returns the ring running the other way, as holes do.
+func reversed(ring []point) []point {
+	r := make([]point, len(ring))
+	for i, p := range ring {
+		r[len(ring)-1-i] = p
+	}
+	return r
+}
+
+// ringArea returns the area of a ring, positive when it runs anticlockwise.
+func ringArea(ring []point) float64 {
+	area := 0.0
+	for i := range ring {
+		area += cross(point{}, ring[i], ring[(i+1)%len(ring)])
+	}
+	return area / 2
+}
+
+func inside(ring []point, p point) bool {
+	in := false
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		if (a.y > p.y) != (b.y > p.y) && p.x < (b.x-a.x)*(p.y-a.y)/(b.y-a.y)+a.x {
+			in = !in
+		}
+	}
+	return in
+}
+
+func TestTriangulate(t *testing.T) {
+	tests := []struct {
+		name  string
+		outer []point
+		holes [][]point
+	}{
+		{"square", square(0, 0, 128), nil},
+		{"square with a hole", square(0, 0, 128), [][]point{reversed(square(32, 32, 64))}},
+		{"two holes", square(0, 0, 256), [][]point{reversed(square(32, 32, 64)), reversed(square(160, 96, 64))}},
+		{"collinear vertexes", []point{{0, 0}, {64, 0}, {128, 0}, {128, 64}, {128, 128}, {64, 128}, {0, 128}, {0, 64}}, nil},
+		{"concave", []point{{0, 0}, {128, 0}, {128, 128}, {64, 32}, {0, 128}}, nil},
+		{"hole in a concave ring", []point{{0, 0}, {192, 0}, {192, 192}, {128, 192}, {128, 64}, {64, 64}, {64, 192}, {0, 192}}, [][]point{reversed(square(16, 16, 16))}},
+	}
+	for _, test := range tests {
+		points := append([]point{}, test.outer...)
+		want := ringArea(test.outer)
+		for _, hole := range test.holes {
+			points = append(points, hole...)
+			want += ringArea(hole)
+		}
+		triangles := triangulate(test.outer, test.holes)
+		got := 0.0
+		for _, tri := range triangles {
+			a, b, c := points[tri[0]], points[tri[1]], points[tri[2]]
+			area := cross(a, b, c) / 2
+			if area <= 0 {
+				t.Errorf("%s: triangle %v has area %g, want it anticlockwise", test.name, tri, area)
+			}
+			got += area
+			centre := point{(a.x + b.x + c.x) / 3, (a.y + b.y + c.y) / 3}
+			if !inside(test.outer, centre) {
+				t.Errorf("%s: triangle %v is outside the polygon", test.name, tri)
+			}
+			for _, hole := range test.holes {
+				if inside(hole, centre) {
+					t.Errorf("%s: triangle %v is inside a hole", test.name, tri)
+				}
+			}
+		}
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: triangles cover an area of %g, want %g", test.name, got, want)
+		}
+	}
+}
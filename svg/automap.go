@@ -0,0 +1,118 @@
+package svg
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// Palette indices of the automap line colours, from am_map.c.
+const (
+	automapWallColour       = 176
+	automapTeleporterColour = 184
+	automapFloorColour      = 64
+	automapCeilingColour    = 231
+	automapUnseenColour     = 99
+)
+
+// automapFallback holds the colours of the Doom palette at the automap
+// indices, used when the WAD has no PLAYPAL.
+var automapFallback = map[int]color.RGBA{
+	automapWallColour:       {R: 255, G: 0, B: 0, A: 0xff},
+	automapTeleporterColour: {R: 187, G: 0, B: 0, A: 0xff},
+	automapFloorColour:      {R: 191, G: 123, B: 75, A: 0xff},
+	automapCeilingColour:    {R: 255, G: 255, B: 0, A: 0xff},
+	automapUnseenColour:     {R: 99, G: 99, B: 99, A: 0xff},
+}
+
+func automapColour(index int, d *wad.Directory) string {
+	c := automapFallback[index]
+	if d != nil {
+		if pal, err := d.Palette(); err == nil {
+			c = pal[index]
+		}
+	}
+	return fmt.Sprintf("rgb(%d,%d,%d)", c.R, c.G, c.B)
+}
+
+// automapLineColour returns the palette index the automap draws a linedef
+// with, or -1 if it is not drawn at all. In the "start" mode only the lines
+// flagged to always show are drawn, as at the start of a level; "allmap"
+// adds the others in grey, as with the computer area map; and "explored"
+// draws every line as if the player had seen it.
+func automapLineColour(m *wad.Map, l wad.LineDef, mode string) int {
+	if l.Flags&uint16(wad.NEVER_SHOWN_ON_AUTOMAP) != 0 {
+		return -1
+	}
+	if mode != "explored" && l.Flags&uint16(wad.ALWAYS_SHOWN_ON_AUTOMAP) == 0 {
+		if mode == "allmap" {
+			return automapUnseenColour
+		}
+		return -1
+	}
+	right, left := m.LineDefSectors(l)
+	if right < 0 || left < 0 || right >= len(m.Sectors) || left >= len(m.Sectors) {
+		return automapWallColour
+	}
+	if l.SpecialType == 39 {
+		return automapTeleporterColour
+	}
+	if l.IsSecret() {
+		return automapWallColour
+	}
+	front, back := m.Sectors[right], m.Sectors[left]
+	if front.FloorHeight != back.FloorHeight {
+		return automapFloorColour
+	}
+	if front.CeilingHeight != back.CeilingHeight {
+		return automapCeilingColour
+	}
+	return -1
+}
+
+// renderAutomap draws the map the way the in-game automap does: lines only,
+// on a black background, coloured by what they separate.
+func renderAutomap(w io.Writer, m *wad.Map, opts *RenderOpts, minX int16, minY int16, width int32, height int32) {
+	fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"black\"/>\n", minX, minY, width, height)
+	colours := make(map[int]string)
+	fmt.Fprintln(w, "    <g fill=\"none\" stroke-width=\"2\" stroke-linecap=\"round\">")
+	for _, l := range m.LineDefs {
+		index := automapLineColour(m, l, opts.AutomapMode)
+		if index < 0 || int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		if _, ok := colours[index]; !ok {
+			colours[index] = automapColour(index, opts.Resources)
+		}
+		start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+		fmt.Fprintf(w, "      <path d=\"M %d %d L %d %d\" stroke=\"%s\"/>\n", start.X, start.Y, end.X, end.Y, colours[index])
+	}
+	fmt.Fprintln(w, "    </g>")
+}
+
+var automapLabels = []struct {
+	index int
+	label string
+}{
+	{automapWallColour, "Wall"},
+	{automapTeleporterColour, "Teleporter"},
+	{automapFloorColour, "Floor height change"},
+	{automapCeilingColour, "Ceiling height change"},
+	{automapUnseenColour, "Not yet seen"},
+}
+
+func automapLegendEntries(m *wad.Map, opts *RenderOpts) []legendEntry {
+	found := make(map[int]bool)
+	for _, l := range m.LineDefs {
+		found[automapLineColour(m, l, opts.AutomapMode)] = true
+	}
+	entries := make([]legendEntry, 0)
+	for _, a := range automapLabels {
+		if found[a.index] {
+			entries = append(entries, legendEntry{a.label, lineSwatch(fmt.Sprintf("stroke=\"%s\" stroke-width=\"3\"", automapColour(a.index, opts.Resources)))})
+		}
+	}
+	return entries
+}
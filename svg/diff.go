@@ -0,0 +1,99 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/wad"
+)
+
+var changeStroke = map[analysis.ChangeKind]string{
+	analysis.ADDED:   "limegreen",
+	analysis.REMOVED: "red",
+	analysis.MOVED:   "orange",
+	analysis.CHANGED: "dodgerblue",
+}
+
+var changeLabels = map[analysis.ChangeKind]string{
+	analysis.ADDED:   "Added",
+	analysis.REMOVED: "Removed",
+	analysis.MOVED:   "Moved",
+	analysis.CHANGED: "Changed",
+}
+
+// diffLegendEntries lists the kinds of change found.
+func diffLegendEntries(d *analysis.MapDiff) []legendEntry {
+	found := make(map[analysis.ChangeKind]bool)
+	for _, c := range d.Changes {
+		found[c.Kind] = true
+	}
+	entries := make([]legendEntry, 0)
+	for _, kind := range []analysis.ChangeKind{analysis.ADDED, analysis.REMOVED, analysis.MOVED, analysis.CHANGED} {
+		if found[kind] {
+			entries = append(entries, legendEntry{changeLabels[kind], lineSwatch(fmt.Sprintf("stroke=\"%s\" stroke-width=\"3\"", changeStroke[kind]))})
+		}
+	}
+	return entries
+}
+
+func diffLine(w io.Writer, m *wad.Map, i int, attributes string, title string) {
+	l := m.LineDefs[i]
+	if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+		return
+	}
+	start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+	fmt.Fprintf(w, "      <path d=\"M %d %d L %d %d\" %s><title>%s</title></path>\n", start.X, start.Y, end.X, end.Y, attributes, title)
+}
+
+// renderDiff marks what changed between the previous version of a map and
+// the one being drawn. Whatever was removed or has moved away is drawn
+// dashed, where it used to be.
+func renderDiff(w io.Writer, old *wad.Map, new *wad.Map, d *analysis.MapDiff, size int) {
+	fmt.Fprintf(w, "    <g fill=\"none\" stroke-width=\"%d\">\n", size/4+1)
+	dash := fmt.Sprintf("stroke-dasharray=\"%d %d\"", size/2+1, size/4+1)
+	for _, c := range d.Changes {
+		stroke := changeStroke[c.Kind]
+		title := html.EscapeString(c.String())
+		switch c.Element {
+		case "sector":
+			if c.Kind == analysis.REMOVED {
+				continue
+			}
+			fmt.Fprintf(w, "    <g fill=\"%s\" fill-opacity=\"0.3\" stroke=\"none\">\n      <title>%s</title>\n", stroke, title)
+			renderAllLineDefs(w, new, new.SectorBoundary(c.New))
+			fmt.Fprintln(w, "    </g>")
+		case "linedef":
+			if c.Old >= 0 && c.Kind != analysis.CHANGED {
+				diffLine(w, old, c.Old, fmt.Sprintf("stroke=\"%s\" %s", stroke, dash), title)
+			}
+			if c.New >= 0 {
+				diffLine(w, new, c.New, fmt.Sprintf("stroke=\"%s\"", stroke), title)
+			}
+		case "vertex":
+			if c.Old >= 0 {
+				v := old.Vertexes[c.Old]
+				fmt.Fprintf(w, "      <circle cx=\"%d\" cy=\"%d\" r=\"%d\" stroke=\"%s\" %s><title>%s</title></circle>\n", v.X, v.Y, size/2, stroke, dash, title)
+			}
+			if c.New >= 0 {
+				v := new.Vertexes[c.New]
+				fmt.Fprintf(w, "      <circle cx=\"%d\" cy=\"%d\" r=\"%d\" stroke=\"%s\"><title>%s</title></circle>\n", v.X, v.Y, size/2, stroke, title)
+			}
+		case "thing":
+			if c.Old >= 0 && c.Kind != analysis.CHANGED {
+				t := old.Things[c.Old]
+				fmt.Fprintf(w, "      <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" stroke=\"%s\" %s><title>%s</title></rect>\n", int(t.XPosition)-size, int(t.YPosition)-size, size*2, size*2, stroke, dash, title)
+			}
+			if c.New >= 0 {
+				t := new.Things[c.New]
+				fmt.Fprintf(w, "      <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" stroke=\"%s\"><title>%s</title></rect>\n", int(t.XPosition)-size, int(t.YPosition)-size, size*2, size*2, stroke, title)
+			}
+			if c.Kind == analysis.MOVED {
+				o, n := old.Things[c.Old], new.Things[c.New]
+				fmt.Fprintf(w, "      <path d=\"M %d %d L %d %d\" stroke=\"%s\" stroke-width=\"1\"/>\n", o.XPosition, o.YPosition, n.XPosition, n.YPosition, stroke)
+			}
+		}
+	}
+	fmt.Fprintln(w, "    </g>")
+}
@@ -0,0 +1,83 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"io"
+	"sort"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+)
+
+// sectorFlat returns the name of the flat a sector is filled with for the
+// given FlatFill mode.
+func sectorFlat(s wad.Sector, mode string) string {
+	if mode == "ceiling" {
+		return s.CeilingTexture
+	}
+	return s.FloorTexture
+}
+
+// loadFlats decodes the flats used by the sectors of the map.
+func loadFlats(m *wad.Map, d *wad.Directory, mode string) map[string]*wad.Flat {
+	flats := make(map[string]*wad.Flat)
+	for _, sector := range m.Sectors {
+		name := sectorFlat(sector, mode)
+		if _, ok := flats[name]; ok || name == "" || name == "-" {
+			continue
+		}
+		l := d.Flat(name)
+		if l == nil {
+			logging.Warn("No flat found", "flat", name)
+			continue
+		}
+		f, err := wad.DecodeFlat(d.ReadLump(l))
+		if err != nil {
+			logging.Warn("Unable to decode flat", "flat", name, "error", err)
+			continue
+		}
+		flats[name] = f
+	}
+	return flats
+}
+
+// renderFlatDefs writes each flat as a pattern tiled from the world origin,
+// so that it lines up with the 64 unit grid as it does in the game.
+func renderFlatDefs(w io.Writer, flats map[string]*wad.Flat, pal wad.Palette) {
+	names := make([]string, 0, len(flats))
+	for name := range flats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "  <defs>")
+	for _, name := range names {
+		buf := &bytes.Buffer{}
+		png.Encode(buf, flats[name].Image(pal))
+		fmt.Fprintf(w, "    <pattern id=\"%s\" patternUnits=\"userSpaceOnUse\" x=\"0\" y=\"0\" width=\"64\" height=\"64\">\n", flatID(name))
+		fmt.Fprintf(w, "      <image width=\"64\" height=\"64\" href=\"data:image/png;base64,%s\"/>\n", base64.StdEncoding.EncodeToString(buf.Bytes()))
+		fmt.Fprintln(w, "    </pattern>")
+	}
+	fmt.Fprintln(w, "  </defs>")
+}
+
+func flatAttributeString(name string) string {
+	return fmt.Sprintf("fill=\"url(#%s)\" stroke=\"black\" fill-opacity=\"1.0\" stroke-width=\"1\"", flatID(name))
+}
+
+// flatID returns the id of a flat's pattern. Flat names may hold any bytes,
+// so all but letters, digits, underscores and hyphens are written as a dot
+// followed by their value in hex.
+func flatID(name string) string {
+	id := []byte("flat-")
+	for _, c := range []byte(name) {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' {
+			id = append(id, c)
+		} else {
+			id = append(id, fmt.Sprintf(".%02x", c)...)
+		}
+	}
+	return string(id)
+}
@@ -0,0 +1,23 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlatID(t *testing.T) {
+	for name, want := range map[string]string{
+		"FLOOR4_8":   "flat-FLOOR4_8",
+		"nukage1":    "flat-nukage1",
+		"A\"B&<#)":   "flat-A.22B.26.3c.23.29",
+		"X.2e":       "flat-X.2e2e",
+		"F_SKY1\xff": "flat-F_SKY1.ff",
+	} {
+		if got := flatID(name); got != want {
+			t.Errorf("flatID(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if attributes := flatAttributeString("\" onload=\"x"); strings.Count(attributes, "\"") != 8 {
+		t.Errorf("flatAttributeString let a quote through: %s", attributes)
+	}
+}
@@ -0,0 +1,160 @@
+package svg
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+const DefaultHeightGradient = "#2c7bb6,#abd9e9,#ffffbf,#fdae61,#d7191c"
+
+// MaxStepHeight is the tallest step the player can climb.
+const MaxStepHeight = 24
+
+// ParseGradient parses a comma separated list of at least two #rrggbb
+// colours.
+func ParseGradient(s string) ([]color.RGBA, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("gradient %q needs at least two colours", s)
+	}
+	gradient := make([]color.RGBA, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if len(p) != 7 || p[0] != '#' {
+			return nil, fmt.Errorf("invalid colour %q, expected #rrggbb", p)
+		}
+		v, err := strconv.ParseUint(p[1:], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid colour %q, expected #rrggbb", p)
+		}
+		gradient = append(gradient, color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff})
+	}
+	return gradient, nil
+}
+
+// sectorHeight returns the height shown for a sector in the given HeightMode.
+func sectorHeight(s wad.Sector, mode string) int {
+	switch mode {
+	case "ceiling":
+		return int(s.CeilingHeight)
+	case "headroom":
+		return int(s.CeilingHeight) - int(s.FloorHeight)
+	}
+	return int(s.FloorHeight)
+}
+
+// heightRange returns the lowest and highest heights in the map.
+func heightRange(m *wad.Map, mode string) (int, int) {
+	low, high := 0, 0
+	for i, s := range m.Sectors {
+		h := sectorHeight(s, mode)
+		if i == 0 || h < low {
+			low = h
+		}
+		if i == 0 || h > high {
+			high = h
+		}
+	}
+	return low, high
+}
+
+// interpolate returns the colour at position t, between 0 and 1, along the
+// gradient.
+func interpolate(gradient []color.RGBA, t float64) color.RGBA {
+	if t <= 0 {
+		return gradient[0]
+	}
+	if t >= 1 {
+		return gradient[len(gradient)-1]
+	}
+	pos := t * float64(len(gradient)-1)
+	i := int(pos)
+	f := pos - float64(i)
+	a, b := gradient[i], gradient[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5)
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 0xff}
+}
+
+// heightScale maps the heights in a map onto a gradient.
+type heightScale struct {
+	mode     string
+	gradient []color.RGBA
+	low      int
+	high     int
+}
+
+func newHeightScale(m *wad.Map, mode string, gradient string) *heightScale {
+	colours, err := ParseGradient(gradient)
+	if err != nil {
+		colours, _ = ParseGradient(DefaultHeightGradient)
+	}
+	low, high := heightRange(m, mode)
+	return &heightScale{mode: mode, gradient: colours, low: low, high: high}
+}
+
+func (h *heightScale) colour(s wad.Sector) color.RGBA {
+	t := 0.0
+	if h.high > h.low {
+		t = float64(sectorHeight(s, h.mode)-h.low) / float64(h.high-h.low)
+	}
+	return interpolate(h.gradient, t)
+}
+
+// renderSteps emphasises the two-sided linedefs whose floor heights differ by
+// more than the player can climb.
+func renderSteps(w io.Writer, m *wad.Map, strokeWidth int) {
+	fmt.Fprintf(w, "    <g stroke=\"black\" stroke-width=\"%d\" stroke-linecap=\"round\">\n", strokeWidth)
+	for _, linedef := range m.LineDefs {
+		if int(linedef.Start) >= len(m.Vertexes) || int(linedef.End) >= len(m.Vertexes) {
+			continue
+		}
+		right, left := m.LineDefSectors(linedef)
+		if right < 0 || left < 0 || right >= len(m.Sectors) || left >= len(m.Sectors) {
+			continue
+		}
+		step := int(m.Sectors[right].FloorHeight) - int(m.Sectors[left].FloorHeight)
+		if step <= MaxStepHeight && step >= -MaxStepHeight {
+			continue
+		}
+		start := m.Vertexes[linedef.Start]
+		end := m.Vertexes[linedef.End]
+		fmt.Fprintf(w, "      <path d=\"M %d %d L %d %d\"><title>Step of %d</title></path>\n", start.X, start.Y, end.X, end.Y, step)
+	}
+	fmt.Fprintln(w, "    </g>")
+}
+
+var heightModeNames = map[string]string{
+	"floor":    "Floor height",
+	"ceiling":  "Ceiling height",
+	"headroom": "Headroom",
+}
+
+// renderHeightLegend draws the gradient scale with its lowest and highest
+// heights, and a sample of the step emphasis, at the given position, and
+// returns the height it took up.
+func renderHeightLegend(w io.Writer, h *heightScale, x int, y int, size int) int {
+	fmt.Fprintln(w, "  <defs>")
+	fmt.Fprintln(w, "    <linearGradient id=\"height-gradient\">")
+	for i, c := range h.gradient {
+		fmt.Fprintf(w, "      <stop offset=\"%.3f\" stop-color=\"rgb(%d,%d,%d)\"/>\n", float64(i)/float64(len(h.gradient)-1), c.R, c.G, c.B)
+	}
+	fmt.Fprintln(w, "    </linearGradient>")
+	fmt.Fprintln(w, "  </defs>")
+	barWidth := size * 10
+	fmt.Fprintf(w, "  <g font-family=\"sans-serif\" font-size=\"%d\">\n", size)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\">%s</text>\n", x, y+size*4/5, heightModeNames[h.mode])
+	fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"url(#height-gradient)\" stroke=\"black\"/>\n", x, y+size*3/2, barWidth, size)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\">%d</text>\n", x, y+size*7/2, h.low)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\" text-anchor=\"end\">%d</text>\n", x+barWidth, y+size*7/2, h.high)
+	fmt.Fprintf(w, "    <path d=\"M %d %d h %d\" stroke=\"black\" stroke-width=\"%d\"/>\n", x, y+size*9/2, size, size/4)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\">Step over %d units</text>\n", x+size*3/2, y+size*9/2+size/3, MaxStepHeight)
+	fmt.Fprintln(w, "  </g>")
+	return size * 11 / 2
+}
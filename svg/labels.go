@@ -0,0 +1,230 @@
+package svg
+
+import (
+	"container/heap"
+	"fmt"
+	"html"
+	"io"
+	"math"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+)
+
+type segment struct {
+	x1, y1, x2, y2 float64
+}
+
+func boundarySegments(m *wad.Map, sector int) []segment {
+	segments := make([]segment, 0)
+	for _, l := range m.SectorBoundary(sector) {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+		segments = append(segments, segment{float64(start.X), float64(start.Y), float64(end.X), float64(end.Y)})
+	}
+	return segments
+}
+
+// signedDistance returns the distance from (x, y) to the nearest segment,
+// negated when the point lies outside the polygon they enclose.
+func signedDistance(x float64, y float64, segments []segment) float64 {
+	inside := false
+	best := math.Inf(1)
+	for _, s := range segments {
+		if (s.y1 > y) != (s.y2 > y) && x < (s.x2-s.x1)*(y-s.y1)/(s.y2-s.y1)+s.x1 {
+			inside = !inside
+		}
+		dx, dy := s.x2-s.x1, s.y2-s.y1
+		t := 0.0
+		if dx != 0 || dy != 0 {
+			t = math.Max(0, math.Min(1, ((x-s.x1)*dx+(y-s.y1)*dy)/(dx*dx+dy*dy)))
+		}
+		px, py := s.x1+t*dx-x, s.y1+t*dy-y
+		best = math.Min(best, px*px+py*py)
+	}
+	if inside {
+		return math.Sqrt(best)
+	}
+	return -math.Sqrt(best)
+}
+
+type cell struct {
+	x, y, half, distance, potential float64
+}
+
+type cellQueue []*cell
+
+func (q cellQueue) Len() int            { return len(q) }
+func (q cellQueue) Less(i, j int) bool  { return q[i].potential > q[j].potential }
+func (q cellQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *cellQueue) Push(x interface{}) { *q = append(*q, x.(*cell)) }
+func (q *cellQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+func newCell(x float64, y float64, half float64, segments []segment) *cell {
+	d := signedDistance(x, y, segments)
+	return &cell{x, y, half, d, d + half*math.Sqrt2}
+}
+
+// poleOfInaccessibility finds the point inside the polygon furthest from its
+// edges, to within precision map units, by subdividing cells that could still
+// hold a better point than the best found so far.
+func poleOfInaccessibility(segments []segment, precision float64) (float64, float64, bool) {
+	if len(segments) == 0 {
+		return 0, 0, false
+	}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, s := range segments {
+		minX, maxX = math.Min(minX, math.Min(s.x1, s.x2)), math.Max(maxX, math.Max(s.x1, s.x2))
+		minY, maxY = math.Min(minY, math.Min(s.y1, s.y2)), math.Max(maxY, math.Max(s.y1, s.y2))
+	}
+	size := math.Min(maxX-minX, maxY-minY)
+	if size <= 0 {
+		return (minX + maxX) / 2, (minY + maxY) / 2, false
+	}
+	queue := &cellQueue{}
+	half := size / 2
+	for x := minX; x < maxX; x += size {
+		for y := minY; y < maxY; y += size {
+			heap.Push(queue, newCell(x+half, y+half, half, segments))
+		}
+	}
+	best := newCell((minX+maxX)/2, (minY+maxY)/2, 0, segments)
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(*cell)
+		if c.distance > best.distance {
+			best = c
+		}
+		if c.potential-best.distance <= precision {
+			continue
+		}
+		h := c.half / 2
+		heap.Push(queue, newCell(c.x-h, c.y-h, h, segments))
+		heap.Push(queue, newCell(c.x+h, c.y-h, h, segments))
+		heap.Push(queue, newCell(c.x-h, c.y+h, h, segments))
+		heap.Push(queue, newCell(c.x+h, c.y+h, h, segments))
+	}
+	return best.x, best.y, best.distance > 0
+}
+
+type box struct {
+	x1, y1, x2, y2 float64
+}
+
+func (b box) overlaps(o box) bool {
+	return b.x1 < o.x2 && o.x1 < b.x2 && b.y1 < o.y2 && o.y1 < b.y2
+}
+
+// labeller places text labels, skipping any that would overlap one already
+// placed.
+type labeller struct {
+	size   int
+	placed []box
+	out    io.Writer
+	colour string
+}
+
+func (l *labeller) textBox(text string, x float64, y float64) box {
+	// Assume an average glyph is a little over half as wide as it is tall.
+	w := float64(len(text)*l.size) * 0.6
+	h := float64(l.size)
+	return box{x - w/2, y - h/2, x + w/2, y + h/2}
+}
+
+// place writes text centred on the first candidate position that is free,
+// and reports whether there was one.
+func (l *labeller) place(text string, candidates [][2]float64) bool {
+	for _, c := range candidates {
+		b := l.textBox(text, c[0], c[1])
+		free := true
+		for _, p := range l.placed {
+			if b.overlaps(p) {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		l.placed = append(l.placed, b)
+		fmt.Fprintf(l.out, "      <text x=\"%.0f\" y=\"%.0f\" fill=\"%s\">%s</text>\n", c[0], c[1], l.colour, html.EscapeString(text))
+		return true
+	}
+	return false
+}
+
+// around returns positions around (x, y), starting with the point itself.
+func around(x float64, y float64, distance float64) [][2]float64 {
+	return [][2]float64{{x, y}, {x, y - distance}, {x, y + distance}, {x + distance, y}, {x - distance, y}}
+}
+
+func renderLabels(w io.Writer, m *wad.Map, opts *RenderOpts, size int) {
+	fmt.Fprintf(w, "    <g font-family=\"sans-serif\" font-size=\"%d\" text-anchor=\"middle\" dominant-baseline=\"central\" stroke=\"white\" stroke-width=\"%d\" paint-order=\"stroke\">\n", size, size/6+1)
+	l := &labeller{size: size, out: w}
+	skipped := 0
+	if opts.LabelSectors {
+		l.colour = "black"
+		for i := range m.Sectors {
+			x, y, ok := poleOfInaccessibility(boundarySegments(m, i), 1)
+			if !ok {
+				continue
+			}
+			if !l.place(fmt.Sprintf("%d", i), around(x, y, float64(size))) {
+				skipped++
+			}
+		}
+	}
+	if opts.LabelTags {
+		l.colour = "darkred"
+		for _, linedef := range m.LineDefs {
+			if linedef.SectorTag == 0 || int(linedef.Start) >= len(m.Vertexes) || int(linedef.End) >= len(m.Vertexes) {
+				continue
+			}
+			start, end := m.Vertexes[linedef.Start], m.Vertexes[linedef.End]
+			x, y := (float64(start.X)+float64(end.X))/2, (float64(start.Y)+float64(end.Y))/2
+			if !l.place(fmt.Sprintf("#%d", linedef.SectorTag), around(x, y, float64(size))) {
+				skipped++
+			}
+		}
+	}
+	if opts.LabelThings {
+		l.colour = "navy"
+		for _, thing := range m.Things {
+			if !isThingShown(thing, opts) {
+				continue
+			}
+			offset := float64(size)
+			if info, ok := thing.Info(); ok {
+				offset += float64(info.Radius)
+			}
+			x, y := float64(thing.XPosition), float64(thing.YPosition)
+			if !l.place(thing.Name(), [][2]float64{{x, y + offset}, {x, y - offset}, {x + offset*2, y}, {x - offset*2, y}}) {
+				skipped++
+			}
+		}
+	}
+	fmt.Fprintln(w, "    </g>")
+	if skipped > 0 {
+		logging.Info("Left out labels that would have overlapped", "count", skipped)
+	}
+}
+
+// isThingShown reports whether renderThing draws the thing with the given
+// options.
+func isThingShown(thing wad.Thing, opts *RenderOpts) bool {
+	if thing.Flags&16 == 16 && !opts.RenderMultiplayer {
+		return false
+	}
+	return (opts.RenderAmmo && thing.IsAmmo()) ||
+		(opts.RenderArtifacts && thing.IsArtifact()) ||
+		(opts.RenderKeys && thing.IsKey()) ||
+		(opts.RenderMonsters && thing.IsMonster()) ||
+		(opts.RenderPowerups && thing.IsPowerup()) ||
+		(opts.RenderWeapons && thing.IsWeapon())
+}
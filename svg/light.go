@@ -0,0 +1,114 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+var lightEffectNames = map[wad.LightEffect]string{
+	wad.LIGHT_FLICKER: "Flickering light",
+	wad.LIGHT_STROBE:  "Strobing light",
+	wad.LIGHT_GLOW:    "Glowing light",
+}
+var lightEffectFill = map[wad.LightEffect]color.RGBA{
+	wad.LIGHT_FLICKER: namedColours["orange"],
+	wad.LIGHT_STROBE:  namedColours["yellow"],
+	wad.LIGHT_GLOW:    namedColours["magenta"],
+}
+
+func isShading(mode string) bool {
+	return mode == "shade" || mode == "colormap"
+}
+
+// colormapShades returns how a white surface looks at each of the 32 light
+// levels of the WAD's COLORMAP.
+func colormapShades(d *wad.Directory) ([]color.RGBA, error) {
+	if d == nil {
+		return nil, errors.New("no WAD to read COLORMAP from")
+	}
+	pal, err := d.Palette()
+	if err != nil {
+		return nil, err
+	}
+	colormaps, err := d.Colormaps()
+	if err != nil {
+		return nil, err
+	}
+	if len(colormaps) < 32 {
+		return nil, fmt.Errorf("COLORMAP has %d maps, expected at least 32", len(colormaps))
+	}
+	white := 0
+	for i, c := range pal {
+		if int(c.R)+int(c.G)+int(c.B) > int(pal[white].R)+int(pal[white].G)+int(pal[white].B) {
+			white = i
+		}
+	}
+	shades := make([]color.RGBA, 32)
+	for i := range shades {
+		shades[i] = pal[colormaps[i][white]]
+	}
+	return shades, nil
+}
+
+// sectorShade returns the colour of a white floor at the sector's light
+// level, either scaled linearly or looked up in the given COLORMAP shades.
+func sectorShade(s wad.Sector, shades []color.RGBA) color.RGBA {
+	light := int(s.LightLevel)
+	if light > 255 {
+		light = 255
+	}
+	if shades == nil {
+		v := uint8(light)
+		return color.RGBA{R: v, G: v, B: v, A: 0xff}
+	}
+	return shades[(255-light)/8]
+}
+
+// renderLightFilterDefs writes a filter for every light level in the map
+// that darkens textured sectors as if lit by their light level.
+func renderLightFilterDefs(w io.Writer, m *wad.Map, shades []color.RGBA) {
+	levels := make(map[uint16]bool)
+	for _, s := range m.Sectors {
+		levels[s.LightLevel] = true
+	}
+	sorted := make([]int, 0, len(levels))
+	for l := range levels {
+		sorted = append(sorted, int(l))
+	}
+	sort.Ints(sorted)
+	fmt.Fprintln(w, "  <defs>")
+	for _, l := range sorted {
+		c := sectorShade(wad.Sector{LightLevel: uint16(l)}, shades)
+		fmt.Fprintf(w, "    <filter id=\"light-%d\"><feComponentTransfer>", l)
+		fmt.Fprintf(w, "<feFuncR type=\"linear\" slope=\"%.3f\"/><feFuncG type=\"linear\" slope=\"%.3f\"/><feFuncB type=\"linear\" slope=\"%.3f\"/>", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+		fmt.Fprintln(w, "</feComponentTransfer></filter>")
+	}
+	fmt.Fprintln(w, "  </defs>")
+}
+
+// renderLightLegend explains the colours of the light effects found in the
+// map, at the given position, and returns the height it took up.
+func renderLightLegend(w io.Writer, m *wad.Map, x int, y int, size int) int {
+	found := make(map[wad.LightEffect]bool)
+	for _, s := range m.Sectors {
+		found[s.LightEffect()] = true
+	}
+	fmt.Fprintf(w, "  <g font-family=\"sans-serif\" font-size=\"%d\">\n", size)
+	row := 0
+	for _, effect := range []wad.LightEffect{wad.LIGHT_FLICKER, wad.LIGHT_STROBE, wad.LIGHT_GLOW} {
+		if !found[effect] {
+			continue
+		}
+		top := y + row*size*3/2
+		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" fill-opacity=\"0.6\" stroke=\"black\" stroke-dasharray=\"8 4\"/>\n", x, top, size, size, svgColour(lightEffectFill[effect]))
+		fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\">%s</text>\n", x+size*3/2, top+size*4/5, lightEffectNames[effect])
+		row++
+	}
+	fmt.Fprintln(w, "  </g>")
+	return row * size * 3 / 2
+}
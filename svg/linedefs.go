@@ -0,0 +1,47 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// renderLineDefTooltips draws an invisible, wide stroke over every linedef,
+// so that hovering over it shows its properties and wall textures.
+func renderLineDefTooltips(w io.Writer, m *wad.Map) {
+	fmt.Fprintln(w, "    <g stroke=\"transparent\" stroke-width=\"6\" fill=\"none\">")
+	for i, linedef := range m.LineDefs {
+		if int(linedef.Start) >= len(m.Vertexes) || int(linedef.End) >= len(m.Vertexes) {
+			continue
+		}
+		start := m.Vertexes[linedef.Start]
+		end := m.Vertexes[linedef.End]
+		fmt.Fprintf(w, "      <path d=\"M %d %d L %d %d\"><title>%s</title></path>\n", start.X, start.Y, end.X, end.Y, html.EscapeString(lineDefDescription(m, i, linedef)))
+	}
+	fmt.Fprintln(w, "    </g>")
+}
+
+func lineDefDescription(m *wad.Map, i int, linedef wad.LineDef) string {
+	desc := strings.Builder{}
+	desc.WriteString(fmt.Sprintf("Linedef %d", i))
+	if linedef.SpecialType != 0 {
+		desc.WriteString(fmt.Sprintf(" (Type %d, Tag %d)", linedef.SpecialType, linedef.SectorTag))
+		if special, ok := linedef.Special(); ok {
+			desc.WriteString("\n" + special.String())
+		}
+	}
+	for _, side := range []struct {
+		label   string
+		sidedef uint16
+	}{{"Front", linedef.RightSideDef}, {"Back", linedef.LeftSideDef}} {
+		if int(side.sidedef) >= len(m.SideDefs) {
+			continue
+		}
+		sd := m.SideDefs[side.sidedef]
+		desc.WriteString(fmt.Sprintf("\n%s: upper %s, middle %s, lower %s", side.label, sd.UpperTextureName, sd.MiddleTextureName, sd.LowerTextureName))
+	}
+	return desc.String()
+}
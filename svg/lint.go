@@ -0,0 +1,27 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/macripps/wad2svg/analysis"
+)
+
+var severityStroke = map[analysis.Severity]string{
+	analysis.ERROR:   "red",
+	analysis.WARNING: "orange",
+	analysis.INFO:    "dodgerblue",
+}
+
+// renderProblems circles every lint problem that has a position on the map.
+func renderProblems(w io.Writer, problems []analysis.Problem, size int) {
+	fmt.Fprintf(w, "    <g fill=\"none\" stroke-width=\"%d\">\n", size/4+1)
+	for _, p := range problems {
+		if !p.Located {
+			continue
+		}
+		fmt.Fprintf(w, "      <circle cx=\"%d\" cy=\"%d\" r=\"%d\" stroke=\"%s\"><title>%s</title></circle>\n", p.X, -p.Y, size, severityStroke[p.Severity], html.EscapeString(p.Severity.String()+": "+p.Message))
+	}
+	fmt.Fprintln(w, "    </g>")
+}
@@ -1,11 +1,15 @@
 package svg
 
 import (
+	"bytes"
 	"fmt"
+	"html"
+	"image/color"
 	"io"
-	"os"
 	"strings"
 
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/logging"
 	"github.com/macripps/wad2svg/wad"
 )
 
@@ -22,13 +26,39 @@ type RenderOpts struct {
 	RenderPowerups    bool
 	RenderWeapons     bool
 	RenderMultiplayer bool
+	RenderSprites     bool
+	FlatFill          string
+	LineDefTooltips   bool
+	LightMode         string
+	HeightMode        string
+	HeightGradient    string
+	ShowLegend        bool
+	ShowScaleBar      bool
+	ShowCompass       bool
+	GridSize          int
+	LabelSectors      bool
+	LabelTags         bool
+	LabelThings       bool
+	AutomapMode       string
+	TagArrows         bool
+	Reachability      bool
+	Lint              bool
+	// Previous is an older version of the map whose changes are drawn, and
+	// Diff those changes, found by analysis.Diff if it is nil.
+	Previous  *wad.Map
+	Diff      *analysis.MapDiff
+	Resources *wad.Directory
 }
 
 func Render(w io.Writer, m *wad.Map, opts *RenderOpts) {
 	minX, minY, maxX, maxY := int16(32767), int16(32767), int16(-32768), int16(-32768)
-	for i := 0; i < len(m.Vertexes); i++ {
-		x := m.Vertexes[i].X
-		y := m.Vertexes[i].Y
+	vertexes := m.Vertexes
+	if opts.Previous != nil {
+		vertexes = append(append([]wad.Vertex{}, m.Vertexes...), opts.Previous.Vertexes...)
+	}
+	for i := 0; i < len(vertexes); i++ {
+		x := vertexes[i].X
+		y := vertexes[i].Y
 		if x < minX {
 			minX = x
 		}
@@ -44,28 +74,208 @@ func Render(w io.Writer, m *wad.Map, opts *RenderOpts) {
 	}
 	width := int32(maxX) - int32(minX)
 	height := int32(maxY) - int32(minY)
-	fmt.Fprintf(os.Stderr, "MinX: %d MaxX: %d Width: %d\nMinY: %d MaxY: %d Height: %d\n", minX, maxX,width, minY, maxY, height)
+	logging.Debug("Map bounds", "min_x", minX, "max_x", maxX, "width", width, "min_y", minY, "max_y", maxY, "height", height)
+	var heights *heightScale
+	if opts.HeightMode != "" {
+		heights = newHeightScale(m, opts.HeightMode, opts.HeightGradient)
+	}
+	var reach *analysis.Reachability
+	if opts.Reachability {
+		reach = analysis.Analyse(m)
+	}
+	var diff *analysis.MapDiff
+	if opts.Previous != nil {
+		diff = opts.Diff
+		if diff == nil {
+			diff = analysis.Diff(opts.Previous, m)
+		}
+	}
+	// Legends, the scale bar and the compass go in a panel to the right of
+	// the map, so that they never hide any of it.
+	size := legendFontSize(width, height)
+	panel := &bytes.Buffer{}
+	panelX, panelY := int(maxX)+size, int(minY)
+	if opts.ShowLegend {
+		plainSectors := heights == nil && opts.FlatFill == "" && opts.LightMode == "" && opts.AutomapMode == ""
+		entries := legendEntries(m, opts, plainSectors, reach)
+		if diff != nil {
+			entries = append(entries, diffLegendEntries(diff)...)
+		}
+		panelY += renderLegend(panel, entries, panelX, panelY, size)
+	}
+	if opts.LightMode == "specials" {
+		panelY += renderLightLegend(panel, m, panelX, panelY+size/2, size) + size/2
+	}
+	if heights != nil {
+		panelY += renderHeightLegend(panel, heights, panelX, panelY+size/2, size) + size/2
+	}
+	if opts.GridSize > 0 && opts.ShowLegend {
+		fmt.Fprintf(panel, "  <text x=\"%d\" y=\"%d\" font-family=\"sans-serif\" font-size=\"%d\">Grid: %d units</text>\n", panelX, panelY+size*3/2, size, opts.GridSize)
+		panelY += size * 2
+	}
+	if opts.ShowScaleBar {
+		panelY += renderScaleBar(panel, width, panelX, panelY+size, size) + size
+	}
+	if opts.ShowCompass {
+		panelY += renderCompass(panel, panelX, panelY+size, size) + size
+	}
+	viewWidth, viewHeight := width, height
+	if panel.Len() > 0 {
+		viewWidth += int32(size * 16)
+		if int32(panelY)-int32(minY) > viewHeight {
+			viewHeight = int32(panelY) - int32(minY)
+		}
+	}
 	fmt.Fprintln(w, "<?xml version=\"1.0\" standalone=\"no\"?>")
-	fmt.Fprintf(w, "<svg width=\"%d\" height=\"%d\" viewBox=\"%d %d %d %d\" xmlns=\"http://www.w3.org/2000/svg\">\n", opts.ImageWidth, opts.ImageHeight, minX, minY, width, height)
+	fmt.Fprintf(w, "<svg width=\"%d\" height=\"%d\" viewBox=\"%d %d %d %d\" xmlns=\"http://www.w3.org/2000/svg\">\n", opts.ImageWidth, opts.ImageHeight, minX, minY, viewWidth, viewHeight)
 	fmt.Fprintf(w, "  <title>%s - %s</title>\n", opts.WadName, opts.MapName)
+	var sprites map[string]*wad.Picture
+	if opts.RenderSprites && opts.Resources != nil {
+		pal, err := opts.Resources.Palette()
+		if err != nil {
+			logging.Warn("Unable to render sprites", "error", err)
+		} else {
+			sprites = loadSprites(m, opts.Resources)
+			renderSpriteDefs(w, sprites, pal)
+		}
+	}
+	var flats map[string]*wad.Flat
+	if opts.FlatFill != "" && opts.Resources != nil {
+		pal, err := opts.Resources.Palette()
+		if err != nil {
+			logging.Warn("Unable to render flats", "error", err)
+		} else {
+			flats = loadFlats(m, opts.Resources, opts.FlatFill)
+			renderFlatDefs(w, flats, pal)
+		}
+	}
+	var shades []color.RGBA
+	if opts.LightMode == "colormap" {
+		var err error
+		if shades, err = colormapShades(opts.Resources); err != nil {
+			logging.Warn("Unable to read COLORMAP, shading linearly instead", "error", err)
+		}
+	}
+	if isShading(opts.LightMode) && len(flats) > 0 {
+		renderLightFilterDefs(w, m, shades)
+	}
 	fmt.Fprintln(w, "  <g fill-rule=\"evenodd\">")
 
-	sectors := m.Sectors
-	for i, sector := range sectors {
-// 		fmt.Fprintf(os.Stderr, "Rendering sector #%d/%d\n", i+1, len(sectors))
-		renderSector(w, m, sector, i)
+	if opts.AutomapMode != "" {
+		renderAutomap(w, m, opts, minX, minY, width, height)
+	} else {
+		sectors := m.Sectors
+		for i, sector := range sectors {
+// 			fmt.Fprintf(os.Stderr, "Rendering sector #%d/%d\n", i+1, len(sectors))
+			renderSector(w, m, sector, i, sectorAttributes(sector, opts, flats, shades, heights))
+		}
+	}
+	if reach != nil {
+		renderUnreachable(w, m, reach, size/2)
+	}
+	if heights != nil {
+		renderSteps(w, m, size/4)
+	}
+	if opts.GridSize > 0 {
+		renderGrid(w, m, opts.GridSize, minX, minY, maxX, maxY)
+	}
+	if opts.LineDefTooltips {
+		renderLineDefTooltips(w, m)
+	}
+	if opts.TagArrows {
+		renderTagArrows(w, m, size/4)
 	}
 	things := m.Things
 	for i, thing := range things {
 // 		fmt.Fprintf(os.Stderr, "Rendering thing #%d/%d\n", i+1, len(things))
-		renderThing(w, thing, i, opts)
+		renderThing(w, thing, i, opts, sprites)
+	}
+	if diff != nil {
+		renderDiff(w, opts.Previous, m, diff, size)
+	}
+	if opts.Lint {
+		var resources *analysis.Resources
+		if opts.Resources != nil {
+			resources = analysis.ReadResources(opts.Resources)
+		}
+		renderProblems(w, analysis.Lint(m, resources), size)
+	}
+	if opts.LabelSectors || opts.LabelTags || opts.LabelThings {
+		renderLabels(w, m, opts, size*2/3)
 	}
 	fmt.Fprintln(w, "  </g>")
+	w.Write(panel.Bytes())
 	fmt.Fprintln(w, "</svg>")
 }
 
-func renderSector(w io.Writer, m *wad.Map, s wad.Sector, i int) {
-	fmt.Fprintf(w, "    <g %s>\n", s.ToSvgAttributeString())
+// sectorAttributes returns the fill and stroke attributes of a sector,
+// taking the height, flat and lighting options into account.
+func sectorAttributes(s wad.Sector, opts *RenderOpts, flats map[string]*wad.Flat, shades []color.RGBA, heights *heightScale) string {
+	attributes := colourAttributes(sectorColours(s, opts, shades, heights))
+	if heights != nil {
+		return attributes
+	}
+	if _, ok := lightEffectFill[s.LightEffect()]; ok && opts.LightMode == "specials" {
+		return attributes + " stroke-dasharray=\"8 4\""
+	}
+	if name := sectorFlat(s, opts.FlatFill); flats[name] != nil {
+		attributes := flatAttributeString(name)
+		if isShading(opts.LightMode) {
+			attributes += fmt.Sprintf(" filter=\"url(#light-%d)\"", s.LightLevel)
+		}
+		return attributes
+	}
+	return attributes
+}
+
+// sectorColours returns the colour a sector is filled with, the opacity of
+// the fill and the colour of its outline, when it is not filled with a flat.
+func sectorColours(s wad.Sector, opts *RenderOpts, shades []color.RGBA, heights *heightScale) (color.RGBA, float64, color.RGBA) {
+	black := namedColours["black"]
+	if heights != nil {
+		return heights.colour(s), 1, black
+	}
+	if fill, ok := lightEffectFill[s.LightEffect()]; ok && opts.LightMode == "specials" {
+		return fill, 0.6, black
+	}
+	if isShading(opts.LightMode) {
+		return sectorShade(s, shades), 1, black
+	}
+	return s.Colours()
+}
+
+// colourAttributes returns the attributes filling and outlining a shape.
+func colourAttributes(fill color.RGBA, opacity float64, stroke color.RGBA) string {
+	return fmt.Sprintf("fill=\"%s\" stroke=\"%s\" fill-opacity=\"%g\" stroke-width=\"1\"", svgColour(fill), svgColour(stroke), opacity)
+}
+
+// svgColour writes a colour as an SVG attribute value, by name if it has
+// one.
+func svgColour(c color.RGBA) string {
+	for name, named := range namedColours {
+		if named == c {
+			return name
+		}
+	}
+	return fmt.Sprintf("rgb(%d,%d,%d)", c.R, c.G, c.B)
+}
+
+// legendFontSize picks a text size, in map units, that stays legible however
+// large the map is.
+func legendFontSize(width int32, height int32) int {
+	size := int(width)
+	if int(height) > size {
+		size = int(height)
+	}
+	size /= 60
+	if size < 16 {
+		size = 16
+	}
+	return size
+}
+
+func renderSector(w io.Writer, m *wad.Map, s wad.Sector, i int, attributes string) {
+	fmt.Fprintf(w, "    <g %s>\n", attributes)
 	fmt.Fprintf(w, "      <title>Sector %d</title>\n", i)
 	fmt.Fprintf(w, "      <desc>Sector Type: %d</desc>\n", s.SectorType)
 	sectorLineDefs := make([]wad.LineDef, 0)
@@ -118,28 +328,52 @@ func renderSpecialLineDefs(w io.Writer, m *wad.Map, lds []wad.LineDef) {
 	for _, linedef := range sectorLineDefs {
 		lineType := linedef.SpecialType
 		if lineType != 0 {
-			stroke := "orange"
+			stroke := lineDefStroke(linedef)
 			strokeWidth := 3
-			if linedef.IsDoor() {
-				stroke = "green"
-			} else if linedef.IsTeleporter() {
-				stroke = "red"
-			} else if linedef.IsLift() {
-				stroke = "blue"
-			} else if linedef.IsExit() {
-				stroke = "purple"
-			} else if linedef.IsSecret() {
-				stroke = "aqua"
+			dash := ""
+			if linedef.IsLockedDoor() {
+				dash = " stroke-dasharray=\"8 4\""
 			}
 			start := m.Vertexes[linedef.Start]
 			end := m.Vertexes[linedef.End]
 
 			fmt.Fprintf(w, "    <!-- Type %d -->\n", linedef.SpecialType)
-			fmt.Fprintf(w, "    <path d=\"M %d %d L %d %d\" stroke=\"%s\" stroke-width=\"%d\" />", start.X, start.Y, end.X, end.Y, stroke, strokeWidth)
+			if special, ok := linedef.Special(); ok {
+				fmt.Fprintf(w, "    <path d=\"M %d %d L %d %d\" stroke=\"%s\" stroke-width=\"%d\"%s><title>%s</title></path>", start.X, start.Y, end.X, end.Y, stroke, strokeWidth, dash, html.EscapeString(special.String()))
+			} else {
+				fmt.Fprintf(w, "    <path d=\"M %d %d L %d %d\" stroke=\"%s\" stroke-width=\"%d\" />", start.X, start.Y, end.X, end.Y, stroke, strokeWidth)
+			}
 		}
 	}
 }
 
+func lineDefStroke(linedef wad.LineDef) string {
+	stroke := "orange"
+	if linedef.IsLockedDoor() {
+		stroke = lockedDoorStroke(linedef.RequiredKey())
+	} else if linedef.IsDoor() {
+		stroke = "green"
+	} else if linedef.IsTeleporter() {
+		stroke = "red"
+	} else if linedef.IsLift() {
+		stroke = "blue"
+	} else if linedef.IsExit() {
+		stroke = "purple"
+	} else if linedef.IsSecret() {
+		stroke = "aqua"
+	}
+	return stroke
+}
+
+// lockedDoorStroke returns the colour of the key that opens a locked door,
+// or grey when any key or a set of keys will do.
+func lockedDoorStroke(key wad.Key) string {
+	if colour := key.Colour(); colour != "" {
+		return colour
+	}
+	return "gray"
+}
+
 func selectLineDef(lineDefs []wad.LineDef, shouldInclude func(wad.LineDef, wad.LineDef) bool) ([]wad.LineDef, []wad.LineDef) {
 	if len(lineDefs) == 1 {
 		return lineDefs, []wad.LineDef{}
@@ -178,7 +412,7 @@ func selectLineDef(lineDefs []wad.LineDef, shouldInclude func(wad.LineDef, wad.L
 	return lineDefGroup, lineDefs
 }
 
-func renderThing(w io.Writer, thing wad.Thing, i int, opts *RenderOpts) {
+func renderThing(w io.Writer, thing wad.Thing, i int, opts *RenderOpts, sprites map[string]*wad.Picture) {
 	if (thing.Flags&16 == 16) && !opts.RenderMultiplayer {
 		return
 	}
@@ -199,187 +433,53 @@ func renderThing(w io.Writer, thing wad.Thing, i int, opts *RenderOpts) {
 		flags = flags + "M"
 	}
 
-	if opts.RenderAmmo && (thing.ThingType == 17 || thing.ThingType == 2007 || thing.ThingType == 2008 || thing.ThingType == 2010 || thing.ThingType == 2046 || thing.ThingType == 2047 || thing.ThingType == 2048 || thing.ThingType == 2049) {
-		colour := "aqua"
-		var ammoType string
-		switch thing.ThingType {
-		case 17:
-			ammoType = "Energy cell pack"
-		case 2007:
-			ammoType = "Clip"
-		case 2008:
-			ammoType = "4 shotgun shells"
-		case 2010:
-			ammoType = "Rocket"
-		case 2046:
-			ammoType = "Box of rockets"
-		case 2047:
-			ammoType = "Energy cell"
-		case 2048:
-			ammoType = "Box of bullets"
-		case 2049:
-			ammoType = "Box of shotgun shells"
+	name := thing.Name()
+	if opts.RenderAmmo && thing.IsAmmo() {
+		if !renderSprite(w, thing, name, flags, sprites) {
+			fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" stroke=\"black\" width=\"20\" height=\"20\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, "aqua", name, flags)
 		}
-		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" stroke=\"black\" width=\"20\" height=\"20\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, colour, ammoType, flags)
 	}
 
-	if opts.RenderArtifacts && (thing.ThingType == 83 || thing.ThingType == 2013 || thing.ThingType == 2014 || thing.ThingType == 2015 || thing.ThingType == 2022 || thing.ThingType == 2023 || thing.ThingType == 2024 || thing.ThingType == 2026 || thing.ThingType == 2045) {
-		colour := "green"
-		var artifactType string
-		switch thing.ThingType {
-		case 83:
-			artifactType = "Megasphere"
-		case 2013:
-			artifactType = "Supercharge"
-		case 2014:
-			artifactType = "Health bonus"
-		case 2015:
-			artifactType = "Armor bonus"
-		case 2022:
-			artifactType = "Invulnerability"
-		case 2023:
-			artifactType = "Berserk"
-		case 2024:
-			artifactType = "Partial invisibility"
-		case 2026:
-			artifactType = "Computer area map"
-		case 2045:
-			artifactType = "Light amplification visor"
+	if opts.RenderArtifacts && thing.IsArtifact() {
+		if !renderSprite(w, thing, name, flags, sprites) {
+			fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" stroke=\"black\" width=\"20\" height=\"20\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, "green", name, flags)
 		}
-		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" stroke=\"black\" width=\"20\" height=\"20\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, colour, artifactType, flags)
 	}
 
-	if opts.RenderKeys && (thing.ThingType == 5 || thing.ThingType == 6 || thing.ThingType == 13 || thing.ThingType == 38 || thing.ThingType == 39 || thing.ThingType == 40) {
-		var colour string
-		var keyType string
-		switch thing.ThingType {
-		case 5:
-			colour = "blue"
-			keyType = "Blue keycard"
-		case 6:
-			colour = "yellow"
-			keyType = "Yellow keycard"
-		case 13:
-			colour = "red"
-			keyType = "Red keycard"
-		case 38:
-			colour = "red"
-			keyType = "Red skull key"
-		case 39:
-			colour = "yellow"
-			keyType = "Yellow skull key"
-		case 40:
-			colour = "blue"
-			keyType = "Blue skull key"
+	if opts.RenderKeys && thing.IsKey() {
+		if !renderSprite(w, thing, name, flags, sprites) {
+			fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"20\" height=\"20\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, keyColour(thing), name, flags)
 		}
-		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"20\" height=\"20\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, colour, keyType, flags)
 	}
 
-	if opts.RenderMonsters && (thing.ThingType == 7 || thing.ThingType == 9 || thing.ThingType == 16 || thing.ThingType == 58 || thing.ThingType == 64 || thing.ThingType == 65 || thing.ThingType == 66 || thing.ThingType == 67 || thing.ThingType == 68 || thing.ThingType == 69 || thing.ThingType == 71 || thing.ThingType == 72 || thing.ThingType == 84 || thing.ThingType == 3001 || thing.ThingType == 3002 || thing.ThingType == 3003 || thing.ThingType == 3004 || thing.ThingType == 3005 || thing.ThingType == 3006) {
-		colour := "black"
-		var radius int
-		var monsterType string
-		switch thing.ThingType {
-		case 7:
-			radius = 128
-			monsterType = "Spiderdemon"
-		case 9:
-			radius = 20
-			monsterType = "Shotgun guy"
-		case 16:
-			radius = 40
-			monsterType = "Cyberdemon"
-		case 58:
-			radius = 30
-			monsterType = "Spectre"
-		case 64:
-			radius = 20
-			monsterType = "Arch-vile"
-		case 65:
-			radius = 20
-			monsterType = "Heavy weapon dude"
-		case 66:
-			radius = 20
-			monsterType = "Revenant"
-		case 67:
-			radius = 48
-			monsterType = "Mancubus"
-		case 68:
-			radius = 64
-			monsterType = "Arachnotron"
-		case 69:
-			radius = 24
-			monsterType = "Hell knight"
-		case 71:
-			radius = 31
-			monsterType = "Pain elemental"
-		case 72:
-			radius = 16
-			monsterType = "Commander Keen"
-		case 84:
-			radius = 20
-			monsterType = "Wolfenstein SS"
-		case 3001:
-			radius = 20
-			monsterType = "Imp"
-		case 3002:
-			radius = 30
-			monsterType = "Demon"
-		case 3003:
-			radius = 24
-			monsterType = "Baron of Hell"
-		case 3004:
-			radius = 20
-			monsterType = "Zombieman"
-		case 3005:
-			radius = 31
-			monsterType = "Cacodemon"
-		case 3006:
-			radius = 16
-			monsterType = "Lost soul"
+	if opts.RenderMonsters && thing.IsMonster() {
+		info, _ := thing.Info()
+		if !renderSprite(w, thing, name, flags, sprites) {
+			fmt.Fprintf(w, "    <circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"%s\"><title>%s [%s]</title></circle>\n", thing.XPosition-10, thing.YPosition-10, info.Radius, "black", name, flags)
 		}
-		fmt.Fprintf(w, "    <circle cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"%s\"><title>%s [%s]</title></circle>\n", thing.XPosition-10, thing.YPosition-10, radius, colour, monsterType, flags)
 	}
 
-	if opts.RenderPowerups && (thing.ThingType == 8 || thing.ThingType == 2011 || thing.ThingType == 2012 || thing.ThingType == 2018 || thing.ThingType == 2019 || thing.ThingType == 2025) {
-		colour := "yellow"
-		var powerUpType string
-		switch thing.ThingType {
-		case 8:
-			powerUpType = "Backpack"
-		case 2011:
-			powerUpType = "Stimpack"
-		case 2012:
-			powerUpType = "Medikit"
-		case 2018:
-			powerUpType = "Armor"
-		case 2019:
-			powerUpType = "Megaarmor"
-		case 2025:
-			powerUpType = "Radiation shielding suit"
+	if opts.RenderPowerups && thing.IsPowerup() {
+		if !renderSprite(w, thing, name, flags, sprites) {
+			fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"20\" height=\"20\" stroke=\"black\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, "yellow", name, flags)
 		}
-		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"20\" height=\"20\" stroke=\"black\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, colour, powerUpType, flags)
 	}
 
-	if opts.RenderWeapons && (thing.ThingType == 82 || thing.ThingType == 2001 || thing.ThingType == 2002 || thing.ThingType == 2003 || thing.ThingType == 2004 || thing.ThingType == 2005 || thing.ThingType == 2006) {
-		colour := "red"
-		var weaponType string
-		switch thing.ThingType {
-		case 82:
-			weaponType = "Super shotgun"
-		case 2001:
-			weaponType = "Shotgun"
-		case 2002:
-			weaponType = "Chaingun"
-		case 2003:
-			weaponType = "Rocket launcher"
-		case 2004:
-			weaponType = "Plasma gun"
-		case 2005:
-			weaponType = "Chainsaw"
-		case 2006:
-			weaponType = "BFG9000"
+	if opts.RenderWeapons && thing.IsWeapon() {
+		if !renderSprite(w, thing, name, flags, sprites) {
+			fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"20\" height=\"20\" stroke=\"black\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, "red", name, flags)
 		}
-		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"20\" height=\"20\" stroke=\"black\" fill=\"%s\"><title>%s [%s]</title></rect>\n", thing.XPosition-10, thing.YPosition-10, colour, weaponType, flags)
 	}
 }
+
+func keyColour(thing wad.Thing) string {
+	switch thing.ThingType {
+	case 5, 40:
+		return "blue"
+	case 6, 39:
+		return "yellow"
+	case 13, 38:
+		return "red"
+	}
+	return "black"
+}
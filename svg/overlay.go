@@ -0,0 +1,215 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/wad"
+)
+
+type legendEntry struct {
+	label  string
+	swatch func(w io.Writer, x int, y int, size int)
+}
+
+func rectSwatch(attributes string) func(io.Writer, int, int, int) {
+	return func(w io.Writer, x int, y int, size int) {
+		fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" %s/>\n", x, y, size, size, attributes)
+	}
+}
+
+func lineSwatch(attributes string) func(io.Writer, int, int, int) {
+	return func(w io.Writer, x int, y int, size int) {
+		fmt.Fprintf(w, "    <path d=\"M %d %d L %d %d\" %s/>\n", x, y+size, x+size, y, attributes)
+	}
+}
+
+func circleSwatch(attributes string) func(io.Writer, int, int, int) {
+	return func(w io.Writer, x int, y int, size int) {
+		fmt.Fprintf(w, "    <circle cx=\"%d\" cy=\"%d\" r=\"%d\" %s/>\n", x+size/2, y+size/2, size/2, attributes)
+	}
+}
+
+var sectorTypeLabels = map[uint16]string{
+	4:  "Damaging floor",
+	5:  "Damaging floor",
+	7:  "Damaging floor",
+	9:  "Secret",
+	10: "Door closes after 30s",
+	11: "Damaging floor, ends level",
+	14: "Door opens after 5m",
+	16: "Damaging floor",
+}
+
+var lineDefStrokeLabels = []struct {
+	stroke string
+	label  string
+}{
+	{"green", "Door"},
+	{"red", "Teleporter"},
+	{"blue", "Lift"},
+	{"purple", "Exit"},
+	{"aqua", "Secret"},
+	{"orange", "Other special"},
+}
+
+var lockedDoorStrokeLabels = []struct {
+	stroke string
+	label  string
+}{
+	{"blue", "Blue locked door"},
+	{"yellow", "Yellow locked door"},
+	{"red", "Red locked door"},
+	{"gray", "Any or several keys door"},
+}
+
+// legendEntries lists what the colours on the map mean, leaving out anything
+// that does not appear on it.
+func legendEntries(m *wad.Map, opts *RenderOpts, plainSectors bool, reach *analysis.Reachability) []legendEntry {
+	entries := make([]legendEntry, 0)
+	if plainSectors {
+		seen := make(map[string]bool)
+		for _, s := range m.Sectors {
+			label, ok := sectorTypeLabels[s.SectorType]
+			if !ok || seen[label] {
+				continue
+			}
+			seen[label] = true
+			entries = append(entries, legendEntry{label, rectSwatch(colourAttributes(s.Colours()))})
+		}
+	}
+	if opts.AutomapMode != "" {
+		entries = append(entries, automapLegendEntries(m, opts)...)
+	} else {
+		strokes := make(map[string]bool)
+		locks := make(map[string]bool)
+		for _, linedef := range m.LineDefs {
+			if linedef.IsLockedDoor() {
+				locks[lineDefStroke(linedef)] = true
+			} else if linedef.SpecialType != 0 {
+				strokes[lineDefStroke(linedef)] = true
+			}
+		}
+		for _, s := range lineDefStrokeLabels {
+			if strokes[s.stroke] {
+				entries = append(entries, legendEntry{s.label, lineSwatch(fmt.Sprintf("stroke=\"%s\" stroke-width=\"3\"", s.stroke))})
+			}
+		}
+		for _, s := range lockedDoorStrokeLabels {
+			if locks[s.stroke] {
+				entries = append(entries, legendEntry{s.label, lineSwatch(fmt.Sprintf("stroke=\"%s\" stroke-width=\"3\" stroke-dasharray=\"8 4\"", s.stroke))})
+			}
+		}
+	}
+	if reach != nil && len(reach.Unreachable()) > 0 {
+		entries = append(entries, legendEntry{"Unreachable", rectSwatch("fill=\"url(#unreachable)\" stroke=\"black\"")})
+	}
+	things := []struct {
+		shown   bool
+		matches func(t *wad.Thing) bool
+		entry   legendEntry
+	}{
+		{opts.RenderAmmo, (*wad.Thing).IsAmmo, legendEntry{"Ammunition", rectSwatch("fill=\"aqua\" stroke=\"black\"")}},
+		{opts.RenderArtifacts, (*wad.Thing).IsArtifact, legendEntry{"Item", rectSwatch("fill=\"green\" stroke=\"black\"")}},
+		{opts.RenderKeys, func(t *wad.Thing) bool { return t.ThingType == 5 || t.ThingType == 40 }, legendEntry{"Blue key", rectSwatch("fill=\"blue\"")}},
+		{opts.RenderKeys, func(t *wad.Thing) bool { return t.ThingType == 6 || t.ThingType == 39 }, legendEntry{"Yellow key", rectSwatch("fill=\"yellow\"")}},
+		{opts.RenderKeys, func(t *wad.Thing) bool { return t.ThingType == 13 || t.ThingType == 38 }, legendEntry{"Red key", rectSwatch("fill=\"red\"")}},
+		{opts.RenderMonsters, (*wad.Thing).IsMonster, legendEntry{"Monster", circleSwatch("fill=\"black\"")}},
+		{opts.RenderPowerups, (*wad.Thing).IsPowerup, legendEntry{"Powerup", rectSwatch("fill=\"yellow\" stroke=\"black\"")}},
+		{opts.RenderWeapons, (*wad.Thing).IsWeapon, legendEntry{"Weapon", rectSwatch("fill=\"red\" stroke=\"black\"")}},
+	}
+	for _, t := range things {
+		if !t.shown {
+			continue
+		}
+		for i := range m.Things {
+			if isThingShown(m.Things[i], opts) && t.matches(&m.Things[i]) {
+				entries = append(entries, t.entry)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// renderLegend writes the legend entries at the given position and returns
+// the height it took up.
+func renderLegend(w io.Writer, entries []legendEntry, x int, y int, size int) int {
+	if len(entries) == 0 {
+		return 0
+	}
+	fmt.Fprintf(w, "  <g font-family=\"sans-serif\" font-size=\"%d\">\n", size)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\" font-weight=\"bold\">Legend</text>\n", x, y+size*4/5)
+	for i, e := range entries {
+		top := y + (i+1)*size*3/2
+		e.swatch(w, x, top, size)
+		fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\">%s</text>\n", x+size*3/2, top+size*4/5, e.label)
+	}
+	fmt.Fprintln(w, "  </g>")
+	return (len(entries) + 1) * size * 3 / 2
+}
+
+// scaleBarLength picks a power of two length, no shorter than a 64 unit
+// grid square, that is about a fifth of the map's width.
+func scaleBarLength(width int32) int {
+	length := 64
+	for int32(length*2) <= width/5 {
+		length *= 2
+	}
+	return length
+}
+
+// renderScaleBar draws a bar of a round number of map units, split in half,
+// and returns the height it took up.
+func renderScaleBar(w io.Writer, width int32, x int, y int, size int) int {
+	length := scaleBarLength(width)
+	fmt.Fprintf(w, "  <g font-family=\"sans-serif\" font-size=\"%d\">\n", size)
+	fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"black\" stroke=\"black\"/>\n", x, y, length/2, size/2)
+	fmt.Fprintf(w, "    <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"white\" stroke=\"black\"/>\n", x+length/2, y, length/2, size/2)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\">0</text>\n", x, y+size*3/2)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\" text-anchor=\"end\">%d units</text>\n", x+length, y+size*3/2, length)
+	fmt.Fprintln(w, "  </g>")
+	return size * 2
+}
+
+// renderCompass draws an arrow pointing north, which is up in both the game
+// and the SVG, and returns the height it took up.
+func renderCompass(w io.Writer, x int, y int, size int) int {
+	cx := x + size
+	fmt.Fprintf(w, "  <g font-family=\"sans-serif\" font-size=\"%d\" text-anchor=\"middle\">\n", size)
+	fmt.Fprintf(w, "    <text x=\"%d\" y=\"%d\" font-weight=\"bold\">N</text>\n", cx, y+size*4/5)
+	fmt.Fprintf(w, "    <path d=\"M %d %d L %d %d L %d %d L %d %d Z\" fill=\"black\"/>\n", cx, y+size, cx+size*2/3, y+size*3, cx, y+size*5/2, cx-size*2/3, y+size*3)
+	fmt.Fprintln(w, "  </g>")
+	return size * 4
+}
+
+// gridOrigin returns the corner of the blockmap in SVG coordinates, falling
+// back to where node builders put it when the map has no BLOCKMAP.
+func gridOrigin(m *wad.Map, minX int16, maxY int16) (int, int) {
+	if m.BlockMap != nil {
+		return int(m.BlockMap.OriginX), -int(m.BlockMap.OriginY)
+	}
+	return int(minX) - 8, int(maxY) + 8
+}
+
+// renderGrid draws grid lines every size units across the map, aligned to
+// the blockmap.
+func renderGrid(w io.Writer, m *wad.Map, size int, minX int16, minY int16, maxX int16, maxY int16) {
+	originX, originY := gridOrigin(m, minX, maxY)
+	first := func(origin int, min int) int {
+		offset := (origin - min) % size
+		if offset < 0 {
+			offset += size
+		}
+		return min + offset
+	}
+	fmt.Fprintln(w, "    <g stroke=\"gray\" stroke-opacity=\"0.5\" stroke-width=\"1\">")
+	for x := first(originX, int(minX)); x <= int(maxX); x += size {
+		fmt.Fprintf(w, "      <path d=\"M %d %d V %d\"/>\n", x, minY, maxY)
+	}
+	for y := first(originY, int(minY)); y <= int(maxY); y += size {
+		fmt.Fprintf(w, "      <path d=\"M %d %d H %d\"/>\n", minX, y, maxX)
+	}
+	fmt.Fprintln(w, "    </g>")
+}
@@ -0,0 +1,325 @@
+package svg
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+)
+
+// View places a map on an image. The map position (x, y), with y increasing
+// downwards as in the SVG, lands on pixel ((x-MinX)*Scale, (y-MinY)*Scale).
+type View struct {
+	MinX  float64
+	MinY  float64
+	Scale float64
+}
+
+func (v View) point(x int16, y int16) (float64, float64) {
+	return (float64(x) - v.MinX) * v.Scale, (float64(y) - v.MinY) * v.Scale
+}
+
+// FitView returns the view that fits the whole map in an image of the given
+// size, centred as the SVG is in its viewport.
+func FitView(m *wad.Map, width int, height int) View {
+	if len(m.Vertexes) == 0 {
+		return View{Scale: 1}
+	}
+	minX, minY, maxX, maxY := m.Vertexes[0].X, m.Vertexes[0].Y, m.Vertexes[0].X, m.Vertexes[0].Y
+	for _, v := range m.Vertexes {
+		if v.X < minX {
+			minX = v.X
+		}
+		if v.Y < minY {
+			minY = v.Y
+		}
+		if v.X > maxX {
+			maxX = v.X
+		}
+		if v.Y > maxY {
+			maxY = v.Y
+		}
+	}
+	mapWidth := float64(maxX) - float64(minX) + 1
+	mapHeight := float64(maxY) - float64(minY) + 1
+	scale := math.Min(float64(width)/mapWidth, float64(height)/mapHeight)
+	return View{
+		MinX:  float64(minX) - (float64(width)/scale-mapWidth)/2,
+		MinY:  float64(minY) - (float64(height)/scale-mapHeight)/2,
+		Scale: scale,
+	}
+}
+
+// RenderPNG writes the map as a PNG of opts.ImageWidth by opts.ImageHeight
+// pixels, for viewers that cannot show SVG.
+func RenderPNG(w io.Writer, m *wad.Map, opts *RenderOpts) error {
+	img := image.NewRGBA(image.Rect(0, 0, opts.ImageWidth, opts.ImageHeight))
+	RenderImage(img, m, opts, FitView(m, opts.ImageWidth, opts.ImageHeight))
+	return png.Encode(w, img)
+}
+
+// RenderImage draws the map onto img as seen through view. Sectors, special
+// linedefs and things are coloured as in the SVG, including the height and
+// light shading. Sprites, flats, the automap style, the overlays, labels and
+// the legend panel are only drawn in the SVG.
+func RenderImage(img *image.RGBA, m *wad.Map, opts *RenderOpts, view View) {
+	sectors := make([]int, len(m.Sectors))
+	for i := range sectors {
+		sectors[i] = i
+	}
+	things := make([]int, len(m.Things))
+	for i := range things {
+		things[i] = i
+	}
+	heights, shades := sectorShading(m, opts)
+	drawImage(img, m, opts, view, newSectorLines(m), sectors, things, heights, shades)
+}
+
+// sectorShading returns what the height and light options need to colour
+// the sectors.
+func sectorShading(m *wad.Map, opts *RenderOpts) (*heightScale, []color.RGBA) {
+	var heights *heightScale
+	if opts.HeightMode != "" {
+		heights = newHeightScale(m, opts.HeightMode, opts.HeightGradient)
+	}
+	var shades []color.RGBA
+	if opts.LightMode == "colormap" {
+		var err error
+		if shades, err = colormapShades(opts.Resources); err != nil {
+			logging.Warn("Unable to read COLORMAP, shading linearly instead", "error", err)
+		}
+	}
+	return heights, shades
+}
+
+// drawImage draws the given sectors and things onto img.
+func drawImage(img *image.RGBA, m *wad.Map, opts *RenderOpts, view View, bySector *sectorLines, sectors []int, things []int, heights *heightScale, shades []color.RGBA) {
+	lineWidth := math.Max(1, view.Scale)
+	for _, i := range sectors {
+		sector := m.Sectors[i]
+		fill, opacity, stroke := sectorColours(sector, opts, shades, heights)
+		edges, lines := bySector.edges[i], bySector.lines[i]
+		fillPolygon(img, m, edges, view, fill, opacity)
+		for _, l := range lines {
+			x0, y0 := view.point(m.Vertexes[l.Start].X, m.Vertexes[l.Start].Y)
+			x1, y1 := view.point(m.Vertexes[l.End].X, m.Vertexes[l.End].Y)
+			drawLine(img, x0, y0, x1, y1, lineWidth, stroke)
+		}
+		for _, l := range lines {
+			if l.SpecialType == 0 {
+				continue
+			}
+			colour, _ := parseColour(lineDefStroke(l))
+			x0, y0 := view.point(m.Vertexes[l.Start].X, m.Vertexes[l.Start].Y)
+			x1, y1 := view.point(m.Vertexes[l.End].X, m.Vertexes[l.End].Y)
+			drawLine(img, x0, y0, x1, y1, math.Max(1, 3*view.Scale), colour)
+		}
+	}
+	for _, i := range things {
+		rasterThing(img, m.Things[i], opts, view)
+	}
+}
+
+// sectorLines holds, for each sector, the linedefs bounding it and all the
+// linedefs with the sector on either side, leaving out those referring to
+// missing vertexes.
+type sectorLines struct {
+	edges [][]wad.LineDef
+	lines [][]wad.LineDef
+}
+
+// newSectorLines sorts the linedefs by sector in one pass over the map.
+func newSectorLines(m *wad.Map) *sectorLines {
+	s := &sectorLines{edges: make([][]wad.LineDef, len(m.Sectors)), lines: make([][]wad.LineDef, len(m.Sectors))}
+	for _, l := range m.LineDefs {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		right, left := m.LineDefSectors(l)
+		if right >= 0 && right < len(m.Sectors) {
+			s.lines[right] = append(s.lines[right], l)
+			if left != right {
+				s.edges[right] = append(s.edges[right], l)
+			}
+		}
+		if left >= 0 && left < len(m.Sectors) && left != right {
+			s.lines[left] = append(s.lines[left], l)
+			s.edges[left] = append(s.edges[left], l)
+		}
+	}
+	return s
+}
+
+// fillPolygon fills the inside of the edges with the even-odd rule, as the
+// SVG does, sampling at the centre of each pixel.
+func fillPolygon(img *image.RGBA, m *wad.Map, edges []wad.LineDef, view View, c color.RGBA, opacity float64) {
+	if len(edges) == 0 {
+		return
+	}
+	type edge struct{ x0, y0, x1, y1 float64 }
+	scaled := make([]edge, 0, len(edges))
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, l := range edges {
+		x0, y0 := view.point(m.Vertexes[l.Start].X, m.Vertexes[l.Start].Y)
+		x1, y1 := view.point(m.Vertexes[l.End].X, m.Vertexes[l.End].Y)
+		scaled = append(scaled, edge{x0, y0, x1, y1})
+		top = math.Min(top, math.Min(y0, y1))
+		bottom = math.Max(bottom, math.Max(y0, y1))
+	}
+	bounds := img.Bounds()
+	startY := int(math.Max(float64(bounds.Min.Y), math.Floor(top)))
+	endY := int(math.Min(float64(bounds.Max.Y), math.Ceil(bottom)))
+	xs := make([]float64, 0)
+	for py := startY; py < endY; py++ {
+		y := float64(py) + 0.5
+		xs = xs[:0]
+		for _, e := range scaled {
+			if (e.y0 > y) != (e.y1 > y) {
+				xs = append(xs, e.x0+(y-e.y0)*(e.x1-e.x0)/(e.y1-e.y0))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			from := int(math.Max(float64(bounds.Min.X), math.Ceil(xs[i]-0.5)))
+			to := int(math.Min(float64(bounds.Max.X-1), math.Floor(xs[i+1]-0.5)))
+			for px := from; px <= to; px++ {
+				blend(img, px, py, c, opacity)
+			}
+		}
+	}
+}
+
+// drawLine draws a line of the given width in pixels, with square ends.
+func drawLine(img *image.RGBA, x0 float64, y0 float64, x1 float64, y1 float64, width float64, c color.RGBA) {
+	steps := int(math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		fillRect(img, x0+(x1-x0)*t-width/2, y0+(y1-y0)*t-width/2, width, width, c)
+	}
+}
+
+// fillRect fills the pixels whose centres lie within the rectangle.
+func fillRect(img *image.RGBA, x float64, y float64, width float64, height float64, c color.RGBA) {
+	r := image.Rect(int(math.Floor(x+0.5)), int(math.Floor(y+0.5)), int(math.Floor(x+width+0.5)), int(math.Floor(y+height+0.5)))
+	if r.Empty() {
+		r.Max = r.Min.Add(image.Pt(1, 1))
+	}
+	r = r.Intersect(img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+// fillCircle fills the pixels whose centres lie within the circle.
+func fillCircle(img *image.RGBA, cx float64, cy float64, radius float64, c color.RGBA) {
+	r := image.Rect(int(math.Floor(cx-radius)), int(math.Floor(cy-radius)), int(math.Ceil(cx+radius)), int(math.Ceil(cy+radius))).Intersect(img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			dx, dy := float64(px)+0.5-cx, float64(py)+0.5-cy
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+// blend paints an opaque colour over a pixel with the given opacity.
+func blend(img *image.RGBA, x int, y int, c color.RGBA, opacity float64) {
+	if opacity >= 1 {
+		img.SetRGBA(x, y, c)
+		return
+	}
+	d := img.RGBAAt(x, y)
+	mix := func(s, d uint8) uint8 {
+		return uint8(float64(s)*opacity + float64(d)*(1-opacity) + 0.5)
+	}
+	img.SetRGBA(x, y, color.RGBA{R: mix(c.R, d.R), G: mix(c.G, d.G), B: mix(c.B, d.B), A: mix(c.A, d.A)})
+}
+
+// rasterThing draws a thing as the SVG does when it has no sprite: a box
+// coloured by what kind of thing it is, or a black circle for a monster.
+func rasterThing(img *image.RGBA, thing wad.Thing, opts *RenderOpts, view View) {
+	if (thing.Flags&16 == 16) && !opts.RenderMultiplayer {
+		return
+	}
+	x, y := view.point(thing.XPosition, thing.YPosition)
+	size := math.Max(2, 20*view.Scale)
+	fill := ""
+	switch {
+	case opts.RenderAmmo && thing.IsAmmo():
+		fill = "aqua"
+	case opts.RenderArtifacts && thing.IsArtifact():
+		fill = "green"
+	case opts.RenderKeys && thing.IsKey():
+		fill = keyColour(thing)
+	case opts.RenderMonsters && thing.IsMonster():
+		info, _ := thing.Info()
+		fillCircle(img, x, y, math.Max(1, float64(info.Radius)*view.Scale), color.RGBA{A: 0xff})
+		return
+	case opts.RenderPowerups && thing.IsPowerup():
+		fill = "yellow"
+	case opts.RenderWeapons && thing.IsWeapon():
+		fill = "red"
+	default:
+		return
+	}
+	c, _ := parseColour(fill)
+	fillRect(img, x-size/2, y-size/2, size, size, color.RGBA{A: 0xff})
+	fillRect(img, x-size/2+1, y-size/2+1, size-2, size-2, c)
+}
+
+var namedColours = map[string]color.RGBA{
+	"black":   {0x00, 0x00, 0x00, 0xff},
+	"white":   {0xff, 0xff, 0xff, 0xff},
+	"red":     {0xff, 0x00, 0x00, 0xff},
+	"green":   {0x00, 0x80, 0x00, 0xff},
+	"blue":    {0x00, 0x00, 0xff, 0xff},
+	"yellow":  {0xff, 0xff, 0x00, 0xff},
+	"aqua":    {0x00, 0xff, 0xff, 0xff},
+	"purple":  {0x80, 0x00, 0x80, 0xff},
+	"orange":  {0xff, 0xa5, 0x00, 0xff},
+	"magenta": {0xff, 0x00, 0xff, 0xff},
+	"gray":    {0x80, 0x80, 0x80, 0xff},
+}
+
+// parseColour understands the colours this package writes into SVG
+// attributes: a few names, #rrggbb and rgb(r,g,b).
+func parseColour(s string) (color.RGBA, bool) {
+	if c, ok := namedColours[s]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(s, "#") {
+		if gradient, err := ParseGradient(s + "," + s); err == nil {
+			return gradient[0], true
+		}
+		return color.RGBA{}, false
+	}
+	if strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")") {
+		parts := strings.Split(s[4:len(s)-1], ",")
+		if len(parts) != 3 {
+			return color.RGBA{}, false
+		}
+		var v [3]uint8
+		for i, p := range parts {
+			n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 8)
+			if err != nil {
+				return color.RGBA{}, false
+			}
+			v[i] = uint8(n)
+		}
+		return color.RGBA{R: v[0], G: v[1], B: v[2], A: 0xff}, true
+	}
+	return color.RGBA{}, false
+}
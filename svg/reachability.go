@@ -0,0 +1,31 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/macripps/wad2svg/analysis"
+	"github.com/macripps/wad2svg/wad"
+)
+
+// renderUnreachable hatches over every sector that a player cannot get to
+// from player 1's start.
+func renderUnreachable(w io.Writer, m *wad.Map, r *analysis.Reachability, size int) {
+	fmt.Fprintln(w, "    <defs>")
+	fmt.Fprintf(w, "      <pattern id=\"unreachable\" patternUnits=\"userSpaceOnUse\" width=\"%d\" height=\"%d\" patternTransform=\"rotate(45)\">\n", size, size)
+	fmt.Fprintf(w, "        <rect width=\"%d\" height=\"%d\" fill=\"black\" fill-opacity=\"0.3\"/>\n", size, size)
+	fmt.Fprintf(w, "        <path d=\"M 0 0 V %d\" stroke=\"black\" stroke-width=\"%d\"/>\n", size, size/4+1)
+	fmt.Fprintln(w, "      </pattern>")
+	fmt.Fprintln(w, "    </defs>")
+	fmt.Fprintln(w, "    <g fill=\"url(#unreachable)\" stroke=\"none\">")
+	for _, s := range r.Unreachable() {
+		lineDefs := m.SectorBoundary(s)
+		if len(lineDefs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "    <g>\n      <title>Sector %d cannot be reached</title>\n", s)
+		renderAllLineDefs(w, m, lineDefs)
+		fmt.Fprintln(w, "    </g>")
+	}
+	fmt.Fprintln(w, "    </g>")
+}
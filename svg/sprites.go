@@ -0,0 +1,68 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"io"
+	"sort"
+
+	"github.com/macripps/wad2svg/logging"
+	"github.com/macripps/wad2svg/wad"
+)
+
+// loadSprites decodes the first frame of the sprite of every thing in the map.
+func loadSprites(m *wad.Map, d *wad.Directory) map[string]*wad.Picture {
+	sprites := make(map[string]*wad.Picture)
+	for _, thing := range m.Things {
+		name := thing.SpriteName()
+		if name == "" {
+			continue
+		}
+		if _, ok := sprites[name]; ok {
+			continue
+		}
+		l := d.SpriteFrame(name)
+		if l == nil {
+			logging.Warn("No sprite found", "sprite", name)
+			continue
+		}
+		p, err := wad.DecodePicture(d.ReadLump(l))
+		if err != nil {
+			logging.Warn("Unable to decode sprite", "lump", l.Name(), "error", err)
+			continue
+		}
+		sprites[name] = p
+	}
+	return sprites
+}
+
+func renderSpriteDefs(w io.Writer, sprites map[string]*wad.Picture, pal wad.Palette) {
+	names := make([]string, 0, len(sprites))
+	for name := range sprites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "  <defs>")
+	for _, name := range names {
+		p := sprites[name]
+		buf := &bytes.Buffer{}
+		png.Encode(buf, p.Image(pal))
+		fmt.Fprintf(w, "    <symbol id=\"sprite-%s\" overflow=\"visible\">\n", name)
+		fmt.Fprintf(w, "      <image x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" href=\"data:image/png;base64,%s\"/>\n", -p.LeftOffset, -p.TopOffset, p.Width, p.Height, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		fmt.Fprintln(w, "    </symbol>")
+	}
+	fmt.Fprintln(w, "  </defs>")
+}
+
+// renderSprite draws thing using its sprite, if one was loaded, and reports
+// whether it did so.
+func renderSprite(w io.Writer, thing wad.Thing, label string, flags string, sprites map[string]*wad.Picture) bool {
+	name := thing.SpriteName()
+	if _, ok := sprites[name]; !ok {
+		return false
+	}
+	fmt.Fprintf(w, "    <use href=\"#sprite-%s\" x=\"%d\" y=\"%d\"><title>%s [%s]</title></use>\n", name, thing.XPosition, thing.YPosition, label, flags)
+	return true
+}
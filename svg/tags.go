@@ -0,0 +1,59 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+func lineDefMidpoint(m *wad.Map, i int) (float64, float64, bool) {
+	l := m.LineDefs[i]
+	if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+		return 0, 0, false
+	}
+	start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+	return (float64(start.X) + float64(end.X)) / 2, (float64(start.Y) + float64(end.Y)) / 2, true
+}
+
+// renderTagArrows draws an arrow from every linedef with a special to each
+// sector, or linedef, that it acts on. Arrows to sectors point at the same
+// spot sector labels go.
+func renderTagArrows(w io.Writer, m *wad.Map, strokeWidth int) {
+	fmt.Fprintln(w, "    <defs>")
+	fmt.Fprintln(w, "      <marker id=\"tag-arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"4\" markerHeight=\"4\" orient=\"auto-start-reverse\">")
+	fmt.Fprintln(w, "        <path d=\"M 0 0 L 10 5 L 0 10 Z\" fill=\"darkred\"/>")
+	fmt.Fprintln(w, "      </marker>")
+	fmt.Fprintln(w, "    </defs>")
+	fmt.Fprintf(w, "    <g stroke=\"darkred\" stroke-width=\"%d\" stroke-opacity=\"0.8\" fill=\"none\" marker-end=\"url(#tag-arrow)\">\n", strokeWidth)
+	centres := make(map[int][2]float64)
+	for _, a := range m.Actions() {
+		x1, y1, ok := lineDefMidpoint(m, a.LineDef)
+		if !ok {
+			continue
+		}
+		title := html.EscapeString(a.Special.String())
+		dash := ""
+		if a.Manual {
+			dash = fmt.Sprintf(" stroke-dasharray=\"%d %d\"", strokeWidth*4, strokeWidth*2)
+		}
+		for _, s := range a.Sectors {
+			c, ok := centres[s]
+			if !ok {
+				x, y, _ := poleOfInaccessibility(boundarySegments(m, s), 1)
+				c = [2]float64{x, y}
+				centres[s] = c
+			}
+			fmt.Fprintf(w, "      <path d=\"M %.0f %.0f L %.0f %.0f\"%s><title>Linedef %d to sector %d: %s</title></path>\n", x1, y1, c[0], c[1], dash, a.LineDef, s, title)
+		}
+		for _, l := range a.LineDefs {
+			x2, y2, ok := lineDefMidpoint(m, l)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(w, "      <path d=\"M %.0f %.0f L %.0f %.0f\" stroke-dasharray=\"%d %d\"><title>Linedef %d to linedef %d: %s</title></path>\n", x1, y1, x2, y2, strokeWidth, strokeWidth, a.LineDef, l, title)
+		}
+	}
+	fmt.Fprintln(w, "    </g>")
+}
@@ -0,0 +1,227 @@
+package svg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/macripps/wad2svg/wad"
+)
+
+// TileSize is the width and height of a tile in pixels.
+const TileSize = 256
+
+// tileIndexCell is the size, in map units, of the cells of the spatial index.
+const tileIndexCell = 512
+
+// thingMargin is how far, in map units, a thing may be drawn from its
+// position: the largest monster radius, plus the offset of its circle.
+const thingMargin = 140
+
+// Tiles cuts a map into square XYZ tiles, as used by Leaflet and OpenLayers.
+// At zoom 0 one tile covers the whole map, and each zoom level doubles the
+// number of tiles across and down. Tile y numbers increase southwards. Tiles
+// may be rendered from several goroutines at once.
+type Tiles struct {
+	m       *wad.Map
+	opts    *RenderOpts
+	minX    float64
+	minY    float64
+	side    float64
+	MaxZoom int
+	index   *spatialIndex
+	heights *heightScale
+	shades  []color.RGBA
+}
+
+// NewTiles prepares to render tiles of the map. MaxZoom is set to the first
+// zoom level with at least two pixels per map unit.
+func NewTiles(m *wad.Map, opts *RenderOpts) *Tiles {
+	t := &Tiles{m: m, opts: opts, side: 1}
+	if len(m.Vertexes) > 0 {
+		b := lineDefBounds(m, m.LineDefs)
+		t.minX, t.minY = b.minX, b.minY
+		t.side = math.Max(1, math.Max(b.maxX-b.minX, b.maxY-b.minY))
+	}
+	for float64(TileSize)*math.Exp2(float64(t.MaxZoom))/t.side < 2 {
+		t.MaxZoom++
+	}
+	t.index = newSpatialIndex(m)
+	t.heights, t.shades = sectorShading(m, opts)
+	return t
+}
+
+// Exists reports whether the tile is within the map's tile pyramid.
+func (t *Tiles) Exists(z int, x int, y int) bool {
+	n := 1 << uint(z)
+	return z >= 0 && z <= t.MaxZoom && x >= 0 && x < n && y >= 0 && y < n
+}
+
+// View returns how the tile sees the map.
+func (t *Tiles) View(z int, x int, y int) View {
+	size := t.side / math.Exp2(float64(z))
+	return View{MinX: t.minX + float64(x)*size, MinY: t.minY + float64(y)*size, Scale: TileSize / size}
+}
+
+// Bounds returns the map area covered by the whole pyramid, in map units
+// with y increasing downwards: the minimum x and y and the side length.
+func (t *Tiles) Bounds() (float64, float64, float64) {
+	return t.minX, t.minY, t.side
+}
+
+// visible returns the sectors and things that may be drawn on the tile.
+func (t *Tiles) visible(view View) ([]int, []int) {
+	size := TileSize / view.Scale
+	return t.index.query(bounds{view.MinX, view.MinY, view.MinX + size, view.MinY + size})
+}
+
+// Empty reports whether there is nothing to draw on the tile.
+func (t *Tiles) Empty(z int, x int, y int) bool {
+	sectors, things := t.visible(t.View(z, x, y))
+	return len(sectors) == 0 && len(things) == 0
+}
+
+// RenderPNG draws the tile as a PNG, as RenderPNG draws the whole map.
+func (t *Tiles) RenderPNG(w io.Writer, z int, x int, y int) error {
+	img := image.NewRGBA(image.Rect(0, 0, TileSize, TileSize))
+	view := t.View(z, x, y)
+	sectors, things := t.visible(view)
+	drawImage(img, t.m, t.opts, view, t.index.sectorLines, sectors, things, t.heights, t.shades)
+	return png.Encode(w, img)
+}
+
+// RenderSVG draws the tile as an SVG holding only the sectors and things
+// that fall within it. Like the PNG tiles, it has no sprites, flats or
+// overlays.
+func (t *Tiles) RenderSVG(w io.Writer, z int, x int, y int) error {
+	view := t.View(z, x, y)
+	size := TileSize / view.Scale
+	sectors, things := t.visible(view)
+	fmt.Fprintln(w, "<?xml version=\"1.0\" standalone=\"no\"?>")
+	fmt.Fprintf(w, "<svg width=\"%d\" height=\"%d\" viewBox=\"%g %g %g %g\" xmlns=\"http://www.w3.org/2000/svg\">\n", TileSize, TileSize, view.MinX, view.MinY, size, size)
+	fmt.Fprintln(w, "  <g fill-rule=\"evenodd\">")
+	for _, i := range sectors {
+		renderSector(w, t.m, t.m.Sectors[i], i, sectorAttributes(t.m.Sectors[i], t.opts, nil, t.shades, t.heights))
+	}
+	for _, i := range things {
+		renderThing(w, t.m.Things[i], i, t.opts, nil)
+	}
+	fmt.Fprintln(w, "  </g>")
+	_, err := fmt.Fprintln(w, "</svg>")
+	return err
+}
+
+// bounds is a rectangle in map units.
+type bounds struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (b bounds) intersects(o bounds) bool {
+	return b.minX <= o.maxX && o.minX <= b.maxX && b.minY <= o.maxY && o.minY <= b.maxY
+}
+
+// lineDefBounds returns the rectangle around the linedefs.
+func lineDefBounds(m *wad.Map, lineDefs []wad.LineDef) bounds {
+	b := bounds{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, l := range lineDefs {
+		for _, v := range []uint16{l.Start, l.End} {
+			if int(v) >= len(m.Vertexes) {
+				continue
+			}
+			x, y := float64(m.Vertexes[v].X), float64(m.Vertexes[v].Y)
+			b = bounds{math.Min(b.minX, x), math.Min(b.minY, y), math.Max(b.maxX, x), math.Max(b.maxY, y)}
+		}
+	}
+	return b
+}
+
+// spatialIndex buckets sectors and things into a grid of cells by their
+// bounding boxes, so that a tile only looks at what is near it.
+type spatialIndex struct {
+	origin       bounds
+	columns      int
+	rows         int
+	sectors      [][]int
+	things       [][]int
+	sectorBounds []bounds
+	thingBounds  []bounds
+	sectorLines  *sectorLines
+}
+
+func newSpatialIndex(m *wad.Map) *spatialIndex {
+	ix := &spatialIndex{origin: lineDefBounds(m, m.LineDefs), sectorLines: newSectorLines(m)}
+	if math.IsInf(ix.origin.minX, 1) {
+		ix.origin = bounds{}
+	}
+	ix.columns = int((ix.origin.maxX-ix.origin.minX)/tileIndexCell) + 1
+	ix.rows = int((ix.origin.maxY-ix.origin.minY)/tileIndexCell) + 1
+	ix.sectors = make([][]int, ix.columns*ix.rows)
+	ix.things = make([][]int, ix.columns*ix.rows)
+	for i, lines := range ix.sectorLines.lines {
+		b := lineDefBounds(m, lines)
+		ix.sectorBounds = append(ix.sectorBounds, b)
+		if !math.IsInf(b.minX, 1) {
+			ix.add(ix.sectors, i, b)
+		}
+	}
+	for i, thing := range m.Things {
+		x, y := float64(thing.XPosition), float64(thing.YPosition)
+		b := bounds{x - thingMargin, y - thingMargin, x + thingMargin, y + thingMargin}
+		ix.thingBounds = append(ix.thingBounds, b)
+		ix.add(ix.things, i, b)
+	}
+	return ix
+}
+
+// cells returns the range of cells the rectangle covers, clamped to the
+// grid.
+func (ix *spatialIndex) cells(b bounds) (int, int, int, int) {
+	clamp := func(v float64, n int) int {
+		c := int(math.Floor(v / tileIndexCell))
+		if c < 0 {
+			return 0
+		}
+		if c >= n {
+			return n - 1
+		}
+		return c
+	}
+	return clamp(b.minX-ix.origin.minX, ix.columns), clamp(b.minY-ix.origin.minY, ix.rows),
+		clamp(b.maxX-ix.origin.minX, ix.columns), clamp(b.maxY-ix.origin.minY, ix.rows)
+}
+
+func (ix *spatialIndex) add(buckets [][]int, i int, b bounds) {
+	x0, y0, x1, y1 := ix.cells(b)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			buckets[y*ix.columns+x] = append(buckets[y*ix.columns+x], i)
+		}
+	}
+}
+
+// query returns, in order, the sectors and things whose bounding boxes
+// intersect the rectangle.
+func (ix *spatialIndex) query(b bounds) ([]int, []int) {
+	x0, y0, x1, y1 := ix.cells(b)
+	collect := func(buckets [][]int, itemBounds []bounds) []int {
+		seen := make(map[int]bool)
+		found := make([]int, 0)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				for _, i := range buckets[y*ix.columns+x] {
+					if !seen[i] && itemBounds[i].intersects(b) {
+						seen[i] = true
+						found = append(found, i)
+					}
+				}
+			}
+		}
+		sort.Ints(found)
+		return found
+	}
+	return collect(ix.sectors, ix.sectorBounds), collect(ix.things, ix.thingBounds)
+}
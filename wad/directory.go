@@ -0,0 +1,206 @@
+package wad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Directory is the table of lumps stored in a WAD file.
+type Directory struct {
+	r     io.ReaderAt
+	id    string
+	Lumps []*LumpPtr
+}
+
+// ReadDirectory reads the header and lump directory of the WAD in r. If the
+// directory cannot be read, the lumps read so far are returned.
+func ReadDirectory(r io.ReaderAt) *Directory {
+	d, _ := readDirectory(r)
+	return d
+}
+
+// readDirectory reads the header and lump directory of the WAD in r,
+// reporting a missing or truncated header or directory.
+func readDirectory(r io.ReaderAt) (*Directory, error) {
+	d := &Directory{r: r, Lumps: make([]*LumpPtr, 0)}
+	var header = make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return d, fmt.Errorf("reading WAD header: %v", err)
+	}
+	if id := string(header[0:4]); id != "IWAD" && id != "PWAD" {
+		return d, fmt.Errorf("not a WAD file, identification is %q", id)
+	}
+	d.id = string(header[0:4])
+	numLumps := binary.LittleEndian.Uint32(header[4:8])
+	offset := int64(binary.LittleEndian.Uint32(header[8:12]))
+	fileSize, sized := readerSize(r)
+	var entry = make([]byte, 16)
+	for i := uint32(0); i < numLumps; i++ {
+		if _, err := r.ReadAt(entry, offset); err != nil {
+			return d, fmt.Errorf("reading directory entry %d of %d: %v", i, numLumps, err)
+		}
+		l := &LumpPtr{
+			offset: binary.LittleEndian.Uint32(entry[0:4]),
+			size:   binary.LittleEndian.Uint32(entry[4:8]),
+			name:   string(entry[8:16]),
+		}
+		if sized && l.size > 0 && int64(l.offset)+int64(l.size) > fileSize {
+			return d, fmt.Errorf("lump %s in directory entry %d runs past the end of the file", l.Name(), i)
+		}
+		d.Lumps = append(d.Lumps, l)
+		offset += 16
+	}
+	return d, nil
+}
+
+// readerSize returns the size of the data in r, if r can tell.
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil {
+			return info.Size(), true
+		}
+	}
+	return 0, false
+}
+
+// IWAD reports whether the WAD is a complete game rather than a patch.
+func (d *Directory) IWAD() bool {
+	return d.id == "IWAD"
+}
+
+// Name returns the lump name without its NUL padding.
+func (l *LumpPtr) Name() string {
+	return trimName([]byte(l.name))
+}
+
+// trimName converts a NUL padded eight character name to a string. Anything
+// after the first NUL is ignored, and the name is upper-cased since the
+// engine compares names without regard to case.
+func trimName(b []byte) string {
+	return strings.ToUpper(trimNUL(b))
+}
+
+// trimNUL converts a NUL padded eight character name to a string as it is
+// written, ignoring anything after the first NUL.
+func trimNUL(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func (l *LumpPtr) Size() uint32 {
+	return l.size
+}
+
+// Find returns the last lump called name, as a later lump overrides an
+// earlier one, or nil if there is no such lump.
+func (d *Directory) Find(name string) *LumpPtr {
+	for i := len(d.Lumps) - 1; i >= 0; i-- {
+		if d.Lumps[i].Name() == name {
+			return d.Lumps[i]
+		}
+	}
+	return nil
+}
+
+// ReadLump returns the contents of l.
+func (d *Directory) ReadLump(l *LumpPtr) []byte {
+	data, _ := d.readLump(l)
+	return data
+}
+
+// readLump returns the contents of l, reporting a lump that runs past the
+// end of the file. The lump's last byte is read first, so that a bogus size
+// from a reader of unknown length is not allocated.
+func (d *Directory) readLump(l *LumpPtr) ([]byte, error) {
+	if l.size == 0 {
+		return []byte{}, nil
+	}
+	if _, err := d.r.ReadAt(make([]byte, 1), int64(l.offset)+int64(l.size)-1); err != nil {
+		return nil, fmt.Errorf("reading lump %s: %v", l.Name(), err)
+	}
+	data := make([]byte, l.size)
+	if _, err := d.r.ReadAt(data, int64(l.offset)); err != nil {
+		return data, fmt.Errorf("reading lump %s: %v", l.Name(), err)
+	}
+	return data, nil
+}
+
+// Namespace returns the lumps between the X_START and X_END markers for the
+// given prefix, e.g. "S" for sprites or "F" for flats. The doubled PWAD forms
+// (SS_START, FF_END, ...) are accepted too, and nested markers are skipped.
+func (d *Directory) Namespace(prefix string) []*LumpPtr {
+	isMarker := func(name, suffix string) bool {
+		return name == prefix+suffix || name == prefix+prefix+suffix
+	}
+	lumps := make([]*LumpPtr, 0)
+	inside := false
+	for _, l := range d.Lumps {
+		name := l.Name()
+		switch {
+		case isMarker(name, "_START"):
+			inside = true
+		case isMarker(name, "_END"):
+			inside = false
+		case inside && l.size == 0 && (strings.HasSuffix(name, "_START") || strings.HasSuffix(name, "_END")):
+		case inside:
+			lumps = append(lumps, l)
+		}
+	}
+	return lumps
+}
+
+var mapLumps = map[string]bool{
+	"THINGS":   true,
+	"LINEDEFS": true,
+	"SIDEDEFS": true,
+	"VERTEXES": true,
+	"SEGS":     true,
+	"SSECTORS": true,
+	"NODES":    true,
+	"SECTORS":  true,
+	"REJECT":   true,
+	"BLOCKMAP": true,
+	"BEHAVIOR": true,
+}
+
+// Global returns the lumps that are neither inside a namespace nor part of a
+// map.
+func (d *Directory) Global() []*LumpPtr {
+	lumps := make([]*LumpPtr, 0)
+	depth := 0
+	for _, l := range d.Lumps {
+		name := l.Name()
+		switch {
+		case l.size == 0 && strings.HasSuffix(name, "_START"):
+			depth++
+		case l.size == 0 && strings.HasSuffix(name, "_END"):
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && l.size > 0 && !mapLumps[name]:
+			lumps = append(lumps, l)
+		}
+	}
+	return lumps
+}
+
+// Maps returns the names of the maps in the WAD, in directory order. A map
+// is a marker lump followed by its THINGS lump.
+func (d *Directory) Maps() []string {
+	maps := make([]string, 0)
+	for i := 0; i+1 < len(d.Lumps); i++ {
+		if d.Lumps[i+1].Name() == "THINGS" && !mapLumps[d.Lumps[i].Name()] {
+			maps = append(maps, d.Lumps[i].Name())
+		}
+	}
+	return maps
+}
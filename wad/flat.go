@@ -0,0 +1,47 @@
+package wad
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Flat is a decoded 64x64 floor or ceiling texture, stored as palette indices
+// row by row.
+type Flat [64 * 64]byte
+
+// DecodeFlat decodes a lump from between the F_START and F_END markers.
+func DecodeFlat(data []byte) (*Flat, error) {
+	if len(data) < 64*64 {
+		return nil, fmt.Errorf("flat is too short: %d bytes", len(data))
+	}
+	f := &Flat{}
+	copy(f[:], data)
+	return f, nil
+}
+
+// Image converts the flat to an image using the given palette.
+func (f *Flat) Image(pal Palette) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i, index := range f {
+		c := pal[index]
+		img.Pix[i*4] = c.R
+		img.Pix[i*4+1] = c.G
+		img.Pix[i*4+2] = c.B
+		img.Pix[i*4+3] = 0xff
+	}
+	return img
+}
+
+// Flat returns the named flat lump, or nil if there is none. Names are
+// compared without regard to case.
+func (d *Directory) Flat(name string) *LumpPtr {
+	name = strings.ToUpper(name)
+	flats := d.Namespace("F")
+	for i := len(flats) - 1; i >= 0; i-- {
+		if flats[i].Name() == name {
+			return flats[i]
+		}
+	}
+	return nil
+}
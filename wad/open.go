@@ -0,0 +1,189 @@
+package wad
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/macripps/wad2svg/logging"
+)
+
+// MapFormat is the layout of the lumps making up a map.
+type MapFormat int
+
+const (
+	// AUTO_FORMAT detects the format from the map's lumps.
+	AUTO_FORMAT MapFormat = iota
+	// DOOM_FORMAT is the original Doom layout, also used by Boom and MBF.
+	DOOM_FORMAT
+	// HEXEN_FORMAT has a BEHAVIOR lump and larger things and linedefs.
+	HEXEN_FORMAT
+)
+
+var mapFormatNames = []string{"auto", "doom", "hexen"}
+
+func (f MapFormat) String() string {
+	return mapFormatNames[f]
+}
+
+type options struct {
+	log    *logging.Logger
+	format MapFormat
+}
+
+// Option changes how a WAD is opened and its maps are loaded.
+type Option func(*options)
+
+// WithLogger sends diagnostics to log instead of the package's default
+// logger.
+func WithLogger(log *logging.Logger) Option {
+	return func(o *options) {
+		o.log = log
+	}
+}
+
+// WithFormat reads maps as format rather than detecting it. Only Doom format
+// maps can be read; a Hexen format map is reported as an error.
+func WithFormat(format MapFormat) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WAD is an opened WAD file. Its maps may be loaded from several goroutines
+// at once.
+type WAD struct {
+	Directory *Directory
+	closer    io.Closer
+	opts      options
+}
+
+// Open opens the WAD file at path and reads its directory. The WAD should be
+// closed once its maps have been loaded.
+func Open(path string, opts ...Option) (*WAD, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWAD(f, opts...)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWAD reads the directory of the WAD in r. r must stay readable while
+// maps are loaded from the WAD.
+func NewWAD(r io.ReaderAt, opts ...Option) (*WAD, error) {
+	w := &WAD{opts: options{log: logging.Default()}}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+	d, err := readDirectory(r)
+	if err != nil {
+		return nil, err
+	}
+	w.Directory = d
+	return w, nil
+}
+
+// Close closes the file opened by Open. It does nothing for a WAD made with
+// NewWAD.
+func (w *WAD) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// Maps returns the names of the maps in the WAD, in directory order.
+func (w *WAD) Maps() []string {
+	return w.Directory.Maps()
+}
+
+// LoadMap reads the map called name. Loading stops early with ctx's error if
+// ctx is cancelled.
+func (w *WAD) LoadMap(ctx context.Context, name string) (*Map, error) {
+	log := w.opts.log
+	lumps := w.mapLumps(name)
+	if lumps == nil {
+		return nil, fmt.Errorf("map %s not found", name)
+	}
+	log.Debug("Found map", "map", lumps[0].Name())
+	format := w.opts.format
+	if format == AUTO_FORMAT {
+		format = DOOM_FORMAT
+		for _, lump := range lumps[1:] {
+			if lump.Name() == "BEHAVIOR" {
+				format = HEXEN_FORMAT
+			}
+		}
+	}
+	if format != DOOM_FORMAT {
+		return nil, fmt.Errorf("map %s is in %s format, which is not supported", name, format)
+	}
+	m := &Map{}
+	for _, lump := range lumps[1:] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var parse func(*Map, []byte, *logging.Logger)
+		var recordSize uint32
+		switch lump.Name() {
+		case "THINGS":
+			parse, recordSize = (*Map).parseThings, 10
+		case "LINEDEFS":
+			parse, recordSize = (*Map).parseLineDefs, 14
+		case "SIDEDEFS":
+			parse, recordSize = (*Map).parseSideDefs, 30
+		case "VERTEXES":
+			parse, recordSize = (*Map).parseVertexes, 4
+		case "SECTORS":
+			parse, recordSize = (*Map).parseSectors, 26
+		case "BLOCKMAP":
+			parse, recordSize = (*Map).parseBlockMap, 2
+		default:
+			continue
+		}
+		data, err := w.Directory.readLump(lump)
+		if err != nil {
+			return nil, fmt.Errorf("map %s: %v", name, err)
+		}
+		if lump.size%recordSize != 0 {
+			log.Warn("Lump size is not a whole number of records", "map", name, "lump", lump.Name(), "size", lump.size)
+		}
+		parse(m, data, log)
+	}
+	return m, nil
+}
+
+// mapLumps returns the marker lump of the map called name followed by its
+// lumps, or nil if there is no such map.
+func (w *WAD) mapLumps(name string) []*LumpPtr {
+	name = trimName([]byte(name))
+	lumps := w.Directory.Lumps
+	for i, lump := range lumps {
+		if lump.Name() != name || mapLumps[name] {
+			continue
+		}
+		end := i + 1
+		for end < len(lumps) && mapLumps[lumps[end].Name()] {
+			end++
+		}
+		return lumps[i:end]
+	}
+	return nil
+}
+
+// LoadMap reads the map called name from the WAD in r. It is a shorthand for
+// NewWAD followed by WAD.LoadMap.
+func LoadMap(ctx context.Context, r io.ReaderAt, name string, opts ...Option) (*Map, error) {
+	w, err := NewWAD(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return w.LoadMap(ctx, name)
+}
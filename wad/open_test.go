@@ -0,0 +1,123 @@
+package wad
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/macripps/wad2svg/logging"
+)
+
+// testWAD returns a PWAD holding the lumps.
+func testWAD(t *testing.T, lumps ...Lump) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := WriteWAD(buf, "PWAD", lumps); err != nil {
+		t.Fatalf("WriteWAD: %v", err)
+	}
+	return buf.Bytes()
+}
+
+var quiet = WithLogger(logging.New(ioutil.Discard, logging.WARN))
+
+// Run with -race to check that one WAD can be shared between goroutines.
+func TestLoadMapParallel(t *testing.T) {
+	data := testWAD(t, append(testMapLumps("MAP01"), testMapLumps("MAP02")...)...)
+	w, err := NewWAD(bytes.NewReader(data), quiet)
+	if err != nil {
+		t.Fatalf("NewWAD: %v", err)
+	}
+	if got, want := w.Maps(), []string{"MAP01", "MAP02"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Maps() = %v, want %v", got, want)
+	}
+	want, err := w.LoadMap(context.Background(), "MAP01")
+	if err != nil {
+		t.Fatalf("LoadMap: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			m, err := w.LoadMap(context.Background(), name)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !reflect.DeepEqual(m, want) {
+				t.Errorf("LoadMap(%s) in parallel read a different map", name)
+			}
+		}(w.Maps()[i%2])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("LoadMap in parallel: %v", err)
+	}
+}
+
+func TestLoadMapErrors(t *testing.T) {
+	good := testWAD(t, testMapLumps("MAP01")...)
+	hexen := testWAD(t, append(testMapLumps("MAP01"), Lump{"BEHAVIOR", []byte{0}})...)
+	tests := []struct {
+		name    string
+		data    []byte
+		mapName string
+		opts    []Option
+		want    string
+	}{
+		{"empty file", nil, "MAP01", nil, "reading WAD header"},
+		{"bad header", append([]byte("JUNK"), good[4:]...), "MAP01", nil, "not a WAD file"},
+		{"truncated directory", good[:len(good)-8], "MAP01", nil, "reading directory entry"},
+		{"missing map", good, "MAP02", nil, "map MAP02 not found"},
+		{"hexen map", hexen, "MAP01", nil, "hexen format"},
+		{"hexen format forced", good, "MAP01", []Option{WithFormat(HEXEN_FORMAT)}, "hexen format"},
+	}
+	for _, test := range tests {
+		_, err := LoadMap(context.Background(), bytes.NewReader(test.data), test.mapName, append(test.opts, quiet)...)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: LoadMap returned %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+// hugeLump returns a WAD whose THINGS lump claims to be nearly 2 GiB long.
+func hugeLump(t *testing.T) []byte {
+	data := testWAD(t, testMapLumps("MAP01")...)
+	directory := int(binary.LittleEndian.Uint32(data[8:12]))
+	binary.LittleEndian.PutUint32(data[directory+16+4:], 0x7fffffff)
+	return data
+}
+
+func TestNewWADLumpPastEnd(t *testing.T) {
+	_, err := NewWAD(bytes.NewReader(hugeLump(t)), quiet)
+	if err == nil || !strings.Contains(err.Error(), "lump THINGS in directory entry 1 runs past the end of the file") {
+		t.Errorf("NewWAD returned %v, want the THINGS lump reported", err)
+	}
+}
+
+// A reader that cannot tell its size still does not allocate a lump it
+// cannot read.
+func TestLoadMapLumpPastEndUnsized(t *testing.T) {
+	r := struct{ io.ReaderAt }{bytes.NewReader(hugeLump(t))}
+	_, err := LoadMap(context.Background(), r, "MAP01", quiet)
+	if err == nil || !strings.Contains(err.Error(), "reading lump THINGS") {
+		t.Errorf("LoadMap returned %v, want an error reading THINGS", err)
+	}
+}
+
+func TestLoadMapCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := LoadMap(ctx, bytes.NewReader(testWAD(t, testMapLumps("MAP01")...)), "MAP01", quiet)
+	if err != context.Canceled {
+		t.Errorf("LoadMap with a cancelled context returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,84 @@
+package wad
+
+import (
+	"errors"
+	"image/color"
+)
+
+// Palette is one of the 256 colour palettes stored in PLAYPAL.
+type Palette [256]color.RGBA
+
+// ReadPalettes decodes every palette in a PLAYPAL lump.
+func ReadPalettes(data []byte) []Palette {
+	palettes := make([]Palette, 0, len(data)/768)
+	for len(data) >= 768 {
+		var p Palette
+		for i := range p {
+			p[i] = color.RGBA{R: data[i*3], G: data[i*3+1], B: data[i*3+2], A: 0xff}
+		}
+		palettes = append(palettes, p)
+		data = data[768:]
+	}
+	return palettes
+}
+
+// Palette returns the first (normal) palette from the PLAYPAL lump.
+func (d *Directory) Palette() (Palette, error) {
+	palettes, err := d.Palettes()
+	if err != nil {
+		return Palette{}, err
+	}
+	return palettes[0], nil
+}
+
+// Palettes returns every palette from the PLAYPAL lump.
+func (d *Directory) Palettes() ([]Palette, error) {
+	l := d.Find("PLAYPAL")
+	if l == nil {
+		return nil, errors.New("no PLAYPAL lump")
+	}
+	palettes := ReadPalettes(d.ReadLump(l))
+	if len(palettes) == 0 {
+		return nil, errors.New("PLAYPAL lump is too short")
+	}
+	return palettes, nil
+}
+
+// Colormap maps palette indices to the index used at one light level. The
+// COLORMAP lump holds 32 of these from brightest to darkest, followed by the
+// invulnerability map and an all black map.
+type Colormap [256]byte
+
+// ReadColormaps decodes every colormap in a COLORMAP lump.
+func ReadColormaps(data []byte) []Colormap {
+	colormaps := make([]Colormap, 0, len(data)/256)
+	for len(data) >= 256 {
+		var c Colormap
+		copy(c[:], data)
+		colormaps = append(colormaps, c)
+		data = data[256:]
+	}
+	return colormaps
+}
+
+// Colormaps returns the colormaps from the COLORMAP lump.
+func (d *Directory) Colormaps() ([]Colormap, error) {
+	l := d.Find("COLORMAP")
+	if l == nil {
+		return nil, errors.New("no COLORMAP lump")
+	}
+	colormaps := ReadColormaps(d.ReadLump(l))
+	if len(colormaps) == 0 {
+		return nil, errors.New("COLORMAP lump is too short")
+	}
+	return colormaps, nil
+}
+
+// Apply returns the palette as it appears through the colormap.
+func (p Palette) Apply(c Colormap) Palette {
+	var lit Palette
+	for i, index := range c {
+		lit[i] = p[index]
+	}
+	return lit
+}
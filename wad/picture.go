@@ -0,0 +1,123 @@
+package wad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+// Picture is a decoded graphic in Doom's column-based picture (patch) format.
+// Pixels holds palette indices row by row; Opaque marks which of them are
+// drawn at all.
+type Picture struct {
+	Width      int
+	Height     int
+	LeftOffset int16
+	TopOffset  int16
+	Pixels     []byte
+	Opaque     []bool
+}
+
+// DecodePicture decodes a lump in the picture format used for patches,
+// sprites and menu graphics.
+func DecodePicture(data []byte) (*Picture, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("picture is too short: %d bytes", len(data))
+	}
+	p := &Picture{
+		Width:      int(binary.LittleEndian.Uint16(data[0:2])),
+		Height:     int(binary.LittleEndian.Uint16(data[2:4])),
+		LeftOffset: int16(binary.LittleEndian.Uint16(data[4:6])),
+		TopOffset:  int16(binary.LittleEndian.Uint16(data[6:8])),
+	}
+	if p.Width == 0 || p.Height == 0 || p.Width > 4096 || p.Height > 4096 || len(data) < 8+4*p.Width {
+		return nil, fmt.Errorf("invalid picture dimensions %dx%d", p.Width, p.Height)
+	}
+	p.Pixels = make([]byte, p.Width*p.Height)
+	p.Opaque = make([]bool, p.Width*p.Height)
+	for x := 0; x < p.Width; x++ {
+		offset := int(binary.LittleEndian.Uint32(data[8+4*x:]))
+		if offset < 8+4*p.Width {
+			return nil, fmt.Errorf("column %d starts inside the picture header", x)
+		}
+		top := -1
+		for {
+			if offset >= len(data) {
+				return nil, fmt.Errorf("column %d runs past the end of the picture", x)
+			}
+			delta := int(data[offset])
+			if delta == 0xff {
+				break
+			}
+			if offset+2 >= len(data) {
+				return nil, fmt.Errorf("column %d runs past the end of the picture", x)
+			}
+			// DeePsea tall patches: a delta not below the previous post's
+			// start is relative to it rather than to the top of the column.
+			if delta <= top {
+				top += delta
+			} else {
+				top = delta
+			}
+			length := int(data[offset+1])
+			start := offset + 3
+			if start+length > len(data) {
+				return nil, fmt.Errorf("column %d runs past the end of the picture", x)
+			}
+			for i := 0; i < length; i++ {
+				y := top + i
+				if y >= p.Height {
+					break
+				}
+				p.Pixels[y*p.Width+x] = data[start+i]
+				p.Opaque[y*p.Width+x] = true
+			}
+			offset = start + length + 1
+		}
+	}
+	return p, nil
+}
+
+// Image converts the picture to an image using the given palette, leaving
+// the undrawn pixels transparent.
+func (p *Picture) Image(pal Palette) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, p.Width, p.Height))
+	for i, index := range p.Pixels {
+		if !p.Opaque[i] {
+			continue
+		}
+		c := pal[index]
+		img.Pix[i*4] = c.R
+		img.Pix[i*4+1] = c.G
+		img.Pix[i*4+2] = c.B
+		img.Pix[i*4+3] = 0xff
+	}
+	return img
+}
+
+// WritePNG writes the picture to w as a PNG. The offsets are kept in a grAb
+// chunk, as understood by ZDoom and SLADE.
+func (p *Picture) WritePNG(w io.Writer, pal Palette) error {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, p.Image(pal)); err != nil {
+		return err
+	}
+	// The signature and IHDR chunk are always the first 33 bytes.
+	encoded := buf.Bytes()
+	chunk := make([]byte, 20)
+	binary.BigEndian.PutUint32(chunk[0:4], 8)
+	copy(chunk[4:8], "grAb")
+	binary.BigEndian.PutUint32(chunk[8:12], uint32(int32(p.LeftOffset)))
+	binary.BigEndian.PutUint32(chunk[12:16], uint32(int32(p.TopOffset)))
+	binary.BigEndian.PutUint32(chunk[16:20], crc32.ChecksumIEEE(chunk[4:16]))
+	for _, b := range [][]byte{encoded[:33], chunk, encoded[33:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
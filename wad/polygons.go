@@ -0,0 +1,170 @@
+package wad
+
+import (
+	"math"
+)
+
+// Polygon is one area of a sector: its outer boundary and the holes in it,
+// each a closed ring of vertexes without the first repeated at the end.
+type Polygon struct {
+	Outer []Vertex
+	Holes [][]Vertex
+}
+
+// SectorPolygons returns the areas making up a sector, traced from its
+// boundary linedefs. Boundaries that do not close are left out. Seen with y
+// increasing northwards, as in Doom, outer rings run anticlockwise and holes
+// clockwise.
+func (m *Map) SectorPolygons(sector int) []Polygon {
+	rings := m.sectorRings(sector)
+	depth := make([]int, len(rings))
+	for i, ring := range rings {
+		x, y := ringTestPoint(ring)
+		for j, other := range rings {
+			if i != j && ringContains(other, x, y) {
+				depth[i]++
+			}
+		}
+	}
+	polygons := make([]Polygon, 0)
+	outer := make([]int, 0)
+	for i, ring := range rings {
+		if depth[i]%2 == 0 {
+			polygons = append(polygons, Polygon{Outer: orientRing(ring, true)})
+			outer = append(outer, i)
+		}
+	}
+	for i, ring := range rings {
+		if depth[i]%2 == 0 {
+			continue
+		}
+		// A hole belongs to the smallest outer ring around it.
+		x, y := ringTestPoint(ring)
+		best, bestArea := -1, math.Inf(1)
+		for p, o := range outer {
+			if depth[o] == depth[i]-1 && ringContains(rings[o], x, y) {
+				if area := math.Abs(ringArea(rings[o])); area < bestArea {
+					best, bestArea = p, area
+				}
+			}
+		}
+		if best >= 0 {
+			polygons[best].Holes = append(polygons[best].Holes, orientRing(ring, false))
+		}
+	}
+	return polygons
+}
+
+// sectorRings chains the boundary linedefs of a sector into closed rings.
+// Where several linedefs meet at a vertex, the walk takes the sharpest turn
+// towards the sector's side of the boundary, so that rings touching at a
+// vertex are kept apart.
+func (m *Map) sectorRings(sector int) [][]Vertex {
+	edges := make([]LineDef, 0)
+	for _, l := range m.SectorBoundary(sector) {
+		if int(l.Start) < len(m.Vertexes) && int(l.End) < len(m.Vertexes) && l.Start != l.End {
+			edges = append(edges, l)
+		}
+	}
+	at := make(map[uint16][]int)
+	for i, l := range edges {
+		at[l.Start] = append(at[l.Start], i)
+		at[l.End] = append(at[l.End], i)
+	}
+	used := make([]bool, len(edges))
+	other := func(l LineDef, v uint16) uint16 {
+		if l.Start == v {
+			return l.End
+		}
+		return l.Start
+	}
+	rings := make([][]Vertex, 0)
+	for first := range edges {
+		if used[first] {
+			continue
+		}
+		used[first] = true
+		start, prev, cur := edges[first].Start, edges[first].Start, edges[first].End
+		// Walking a linedef from start to end, its right side is on the
+		// right.
+		right, _ := m.LineDefSectors(edges[first])
+		sectorOnRight := right == sector
+		ring := []Vertex{m.Vertexes[start]}
+		for cur != start {
+			ring = append(ring, m.Vertexes[cur])
+			inX := float64(m.Vertexes[cur].X) - float64(m.Vertexes[prev].X)
+			inY := float64(m.Vertexes[cur].Y) - float64(m.Vertexes[prev].Y)
+			next, bestTurn := -1, math.Inf(-1)
+			for _, e := range at[cur] {
+				if used[e] {
+					continue
+				}
+				v := m.Vertexes[other(edges[e], cur)]
+				outX := float64(v.X) - float64(m.Vertexes[cur].X)
+				outY := float64(v.Y) - float64(m.Vertexes[cur].Y)
+				// The map's y increases southwards, so a left turn has a
+				// negative cross product.
+				turn := math.Atan2(-(inX*outY - inY*outX), inX*outX+inY*outY)
+				if sectorOnRight {
+					turn = -turn
+				}
+				if turn > bestTurn {
+					next, bestTurn = e, turn
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			prev, cur = cur, other(edges[next], cur)
+		}
+		if cur == start && len(ring) >= 3 {
+			rings = append(rings, ring)
+		}
+	}
+	return rings
+}
+
+// ringArea returns the signed area of a ring, positive when it runs
+// anticlockwise with y increasing northwards.
+func ringArea(ring []Vertex) float64 {
+	area := 0.0
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		// Flip the map's y back to Doom's.
+		area += float64(a.X)*-float64(b.Y) - float64(b.X)*-float64(a.Y)
+	}
+	return area / 2
+}
+
+// orientRing returns the ring running anticlockwise, or clockwise, with y
+// increasing northwards.
+func orientRing(ring []Vertex, anticlockwise bool) []Vertex {
+	if (ringArea(ring) > 0) == anticlockwise {
+		return ring
+	}
+	reversed := make([]Vertex, len(ring))
+	for i, v := range ring {
+		reversed[len(ring)-1-i] = v
+	}
+	return reversed
+}
+
+// ringTestPoint returns the middle of the ring's first side, a point on the
+// ring that no other ring of the sector passes through.
+func ringTestPoint(ring []Vertex) (float64, float64) {
+	return (float64(ring[0].X) + float64(ring[1].X)) / 2, (float64(ring[0].Y) + float64(ring[1].Y)) / 2
+}
+
+// ringContains reports whether the point is inside the ring.
+func ringContains(ring []Vertex, x float64, y float64) bool {
+	inside := false
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		x1, y1, x2, y2 := float64(a.X), float64(a.Y), float64(b.X), float64(b.Y)
+		if (y1 > y) != (y2 > y) && x < (x2-x1)*(y-y1)/(y2-y1)+x1 {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,120 @@
+package wad
+
+import "testing"
+
+// polygonMap returns a map with the given number of sectors, sidedef i
+// facing sector i, and no linedefs yet.
+func polygonMap(sectors int) *Map {
+	m := &Map{Sectors: make([]Sector, sectors)}
+	for i := 0; i < sectors; i++ {
+		m.SideDefs = append(m.SideDefs, SideDef{SectorNumber: uint16(i)})
+	}
+	return m
+}
+
+// addLoop adds a closed loop of linedefs through the points, given with y
+// increasing northwards, with sector right on the right and left on the
+// left, or no left side if left is noSide.
+func addLoop(m *Map, right uint16, left uint16, points ...[2]int16) {
+	first := uint16(len(m.Vertexes))
+	for _, p := range points {
+		m.Vertexes = append(m.Vertexes, Vertex{X: p[0], Y: -p[1]})
+	}
+	for i := range points {
+		start, end := first+uint16(i), first+uint16((i+1)%len(points))
+		m.LineDefs = append(m.LineDefs, LineDef{Start: start, End: end, RightSideDef: right, LeftSideDef: left})
+	}
+}
+
+const noSide = 0xffff
+
+func square(x int16, y int16, side int16) [][2]int16 {
+	return [][2]int16{{x, y}, {x, y + side}, {x + side, y + side}, {x + side, y}}
+}
+
+// checkRing checks that the ring has the given number of vertexes and area,
+// and runs anticlockwise for an outer ring or clockwise for a hole.
+func checkRing(t *testing.T, what string, ring []Vertex, vertexes int, area float64, outer bool) {
+	t.Helper()
+	if len(ring) != vertexes {
+		t.Errorf("%s has %d vertexes, want %d", what, len(ring), vertexes)
+	}
+	got := ringArea(ring)
+	if !outer {
+		area = -area
+	}
+	if got != area {
+		t.Errorf("%s has signed area %g, want %g", what, got, area)
+	}
+}
+
+func TestSectorPolygonsIsland(t *testing.T) {
+	m := polygonMap(2)
+	addLoop(m, 0, noSide, square(0, 0, 128)...)
+	addLoop(m, 0, 1, square(32, 32, 64)...)
+	polygons := m.SectorPolygons(0)
+	if len(polygons) != 1 {
+		t.Fatalf("sector 0 has %d polygons, want 1", len(polygons))
+	}
+	checkRing(t, "outer ring", polygons[0].Outer, 4, 128*128, true)
+	if len(polygons[0].Holes) != 1 {
+		t.Fatalf("sector 0 has %d holes, want 1", len(polygons[0].Holes))
+	}
+	checkRing(t, "hole", polygons[0].Holes[0], 4, 64*64, false)
+
+	polygons = m.SectorPolygons(1)
+	if len(polygons) != 1 || len(polygons[0].Holes) != 0 {
+		t.Fatalf("island sector has polygons %v, want one without holes", polygons)
+	}
+	checkRing(t, "island", polygons[0].Outer, 4, 64*64, true)
+}
+
+func TestSectorPolygonsSplit(t *testing.T) {
+	m := polygonMap(1)
+	addLoop(m, 0, noSide, square(0, 0, 64)...)
+	addLoop(m, 0, noSide, square(256, 0, 128)...)
+	polygons := m.SectorPolygons(0)
+	if len(polygons) != 2 {
+		t.Fatalf("sector has %d polygons, want 2", len(polygons))
+	}
+	areas := map[float64]bool{}
+	for i, p := range polygons {
+		if len(p.Holes) != 0 {
+			t.Errorf("polygon %d has %d holes, want none", i, len(p.Holes))
+		}
+		checkRing(t, "polygon", p.Outer, 4, ringArea(p.Outer), true)
+		if ringArea(p.Outer) <= 0 {
+			t.Errorf("polygon %d runs clockwise", i)
+		}
+		areas[ringArea(p.Outer)] = true
+	}
+	if !areas[64*64] || !areas[128*128] {
+		t.Errorf("polygon areas are %v, want %d and %d", areas, 64*64, 128*128)
+	}
+}
+
+func TestSectorPolygonsTouchingAtVertex(t *testing.T) {
+	// Two squares meeting at (64, 64), sharing that vertex, walked both ways
+	// round with the sector on either side of the linedefs.
+	for _, flip := range []bool{false, true} {
+		m := polygonMap(2)
+		m.Vertexes = []Vertex{{0, 0}, {64, 0}, {64, -64}, {0, -64}, {128, -64}, {128, -128}, {64, -128}}
+		for _, l := range [][2]uint16{{0, 3}, {3, 2}, {2, 1}, {1, 0}, {2, 6}, {6, 5}, {5, 4}, {4, 2}} {
+			if flip {
+				m.LineDefs = append(m.LineDefs, LineDef{Start: l[1], End: l[0], RightSideDef: 1, LeftSideDef: 0})
+			} else {
+				m.LineDefs = append(m.LineDefs, LineDef{Start: l[0], End: l[1], RightSideDef: 0, LeftSideDef: noSide})
+			}
+		}
+		polygons := m.SectorPolygons(0)
+		if len(polygons) != 2 {
+			t.Fatalf("flipped %v: sector has %d polygons, want 2", flip, len(polygons))
+		}
+		for i, p := range polygons {
+			if len(p.Holes) != 0 {
+				t.Errorf("flipped %v: polygon %d has %d holes, want none", flip, i, len(p.Holes))
+			}
+			checkRing(t, "polygon", p.Outer, 4, 64*64, true)
+		}
+	}
+}
@@ -0,0 +1,581 @@
+package wad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trigger is how a linedef special is activated: walking over it (W),
+// switching it (S), shooting it (G) or using it as a door (D), once (1) or
+// repeatedly (R).
+type Trigger int
+
+const (
+	NO_TRIGGER Trigger = iota
+	W1
+	WR
+	S1
+	SR
+	G1
+	GR
+	D1
+	DR
+)
+
+var triggerNames = []string{"", "W1", "WR", "S1", "SR", "G1", "GR", "D1", "DR"}
+
+func (t Trigger) String() string {
+	return triggerNames[t]
+}
+
+// Repeatable reports whether the special can be activated more than once.
+func (t Trigger) Repeatable() bool {
+	return t == WR || t == SR || t == GR || t == DR
+}
+
+// SpecialCategory groups linedef specials by what they do.
+type SpecialCategory int
+
+const (
+	OTHER_SPECIAL SpecialCategory = iota
+	DOOR
+	LOCKED_DOOR
+	LIFT
+	PLATFORM
+	ELEVATOR
+	FLOOR
+	CEILING
+	CRUSHER
+	STAIRS
+	DONUT
+	LIGHT
+	EXIT
+	TELEPORT
+	TEXTURE_CHANGE
+	SCROLL
+	TRANSFER
+)
+
+var specialCategoryNames = []string{"Other", "Door", "Locked door", "Lift", "Platform", "Elevator", "Floor", "Ceiling", "Crusher", "Stairs", "Donut", "Light", "Exit", "Teleport", "Texture change", "Scroll", "Transfer"}
+
+func (c SpecialCategory) String() string {
+	return specialCategoryNames[c]
+}
+
+// Speed is how fast the floor, ceiling or door a special moves travels.
+type Speed int
+
+const (
+	NO_SPEED Speed = iota
+	SLOW
+	NORMAL
+	FAST
+	TURBO
+	INSTANT
+)
+
+var speedNames = []string{"", "Slow", "Normal", "Fast", "Turbo", "Instant"}
+
+func (s Speed) String() string {
+	return speedNames[s]
+}
+
+// Key is the key, or combination of keys, needed to activate a special.
+// The plain colours accept either the keycard or the skull key.
+type Key int
+
+const (
+	NO_KEY Key = iota
+	BLUE_KEY
+	YELLOW_KEY
+	RED_KEY
+	BLUE_CARD
+	YELLOW_CARD
+	RED_CARD
+	BLUE_SKULL
+	YELLOW_SKULL
+	RED_SKULL
+	ANY_KEY
+	ALL_THREE_KEYS
+	ALL_SIX_KEYS
+)
+
+var keyNames = []string{"", "Blue key", "Yellow key", "Red key", "Blue keycard", "Yellow keycard", "Red keycard", "Blue skull key", "Yellow skull key", "Red skull key", "Any key", "All three keys", "All six keys"}
+
+func (k Key) String() string {
+	return keyNames[k]
+}
+
+// Colour returns the colour of the key, or "" for no key or a combination
+// of keys.
+func (k Key) Colour() string {
+	switch k {
+	case BLUE_KEY, BLUE_CARD, BLUE_SKULL:
+		return "blue"
+	case YELLOW_KEY, YELLOW_CARD, YELLOW_SKULL:
+		return "yellow"
+	case RED_KEY, RED_CARD, RED_SKULL:
+		return "red"
+	}
+	return ""
+}
+
+// Special describes what a linedef special does. Monsters is set when
+// monsters can activate it too, and MonstersOnly when players cannot.
+type Special struct {
+	Type         uint16
+	Trigger      Trigger
+	Category     SpecialCategory
+	Description  string
+	Speed        Speed
+	Key          Key
+	Target       string
+	Monsters     bool
+	MonstersOnly bool
+	Generalized  bool
+}
+
+func (s Special) String() string {
+	desc := strings.Builder{}
+	if s.Trigger != NO_TRIGGER {
+		desc.WriteString(s.Trigger.String())
+		desc.WriteString(" ")
+	}
+	desc.WriteString(s.Description)
+	details := make([]string, 0)
+	if s.Target != "" {
+		details = append(details, "to "+s.Target)
+	}
+	if s.Speed != NO_SPEED {
+		details = append(details, strings.ToLower(s.Speed.String())+" speed")
+	}
+	if s.Key != NO_KEY {
+		details = append(details, "needs "+strings.ToLower(s.Key.String()))
+	}
+	if s.Monsters {
+		details = append(details, "monsters can activate")
+	}
+	if len(details) > 0 {
+		desc.WriteString(" (" + strings.Join(details, ", ") + ")")
+	}
+	return desc.String()
+}
+
+type specialDef struct {
+	trigger      Trigger
+	category     SpecialCategory
+	speed        Speed
+	key          Key
+	monsters     bool
+	monstersOnly bool
+	description  string
+	target       string
+}
+
+// specials lists the Doom, Doom II, Boom and MBF linedef specials other than
+// the generalized ones.
+var specials = map[uint16]specialDef{
+	1:   {DR, DOOR, NORMAL, NO_KEY, true, false, "Door open, wait, close", ""},
+	2:   {W1, DOOR, NORMAL, NO_KEY, false, false, "Door open and stay", ""},
+	3:   {W1, DOOR, NORMAL, NO_KEY, false, false, "Door close and stay", ""},
+	4:   {W1, DOOR, NORMAL, NO_KEY, true, false, "Door open, wait, close", ""},
+	5:   {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "lowest neighbour ceiling"},
+	6:   {W1, CRUSHER, FAST, NO_KEY, false, false, "Crusher start", ""},
+	7:   {S1, STAIRS, SLOW, NO_KEY, false, false, "Stairs raise by 8", ""},
+	8:   {W1, STAIRS, SLOW, NO_KEY, false, false, "Stairs raise by 8", ""},
+	9:   {S1, DONUT, SLOW, NO_KEY, false, false, "Donut", ""},
+	10:  {W1, LIFT, FAST, NO_KEY, true, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	11:  {S1, EXIT, NO_SPEED, NO_KEY, false, false, "Exit level", ""},
+	12:  {W1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "brightest neighbour"},
+	13:  {W1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "255"},
+	14:  {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 32, change texture", ""},
+	15:  {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture", ""},
+	16:  {W1, DOOR, NORMAL, NO_KEY, false, false, "Door close, wait 30s, open", ""},
+	17:  {W1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light start blinking", ""},
+	18:  {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	19:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "highest neighbour floor"},
+	20:  {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise, change texture", "next higher neighbour floor"},
+	21:  {S1, LIFT, FAST, NO_KEY, false, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	22:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise, change texture", "next higher neighbour floor"},
+	23:  {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "lowest neighbour floor"},
+	24:  {G1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "lowest neighbour ceiling"},
+	25:  {W1, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start", ""},
+	26:  {DR, LOCKED_DOOR, NORMAL, BLUE_KEY, false, false, "Door open, wait, close", ""},
+	27:  {DR, LOCKED_DOOR, NORMAL, YELLOW_KEY, false, false, "Door open, wait, close", ""},
+	28:  {DR, LOCKED_DOOR, NORMAL, RED_KEY, false, false, "Door open, wait, close", ""},
+	29:  {S1, DOOR, NORMAL, NO_KEY, false, false, "Door open, wait, close", ""},
+	30:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "shortest lower texture"},
+	31:  {D1, DOOR, NORMAL, NO_KEY, false, false, "Door open and stay", ""},
+	32:  {D1, LOCKED_DOOR, NORMAL, BLUE_KEY, false, false, "Door open and stay", ""},
+	33:  {D1, LOCKED_DOOR, NORMAL, RED_KEY, false, false, "Door open and stay", ""},
+	34:  {D1, LOCKED_DOOR, NORMAL, YELLOW_KEY, false, false, "Door open and stay", ""},
+	35:  {W1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "35"},
+	36:  {W1, FLOOR, FAST, NO_KEY, false, false, "Floor lower", "8 above highest neighbour floor"},
+	37:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower, change texture and type", "lowest neighbour floor"},
+	38:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "lowest neighbour floor"},
+	39:  {W1, TELEPORT, NO_SPEED, NO_KEY, true, false, "Teleport", ""},
+	40:  {W1, CEILING, SLOW, NO_KEY, false, false, "Ceiling raise", "highest neighbour ceiling"},
+	41:  {S1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "floor"},
+	42:  {SR, DOOR, NORMAL, NO_KEY, false, false, "Door close and stay", ""},
+	43:  {SR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "floor"},
+	44:  {W1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "8 above floor"},
+	45:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "highest neighbour floor"},
+	46:  {GR, DOOR, NORMAL, NO_KEY, false, false, "Door open and stay", ""},
+	47:  {G1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise, change texture", "next higher neighbour floor"},
+	48:  {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll wall left", ""},
+	49:  {S1, CRUSHER, SLOW, NO_KEY, false, false, "Ceiling crush and raise", "8 above floor"},
+	50:  {S1, DOOR, NORMAL, NO_KEY, false, false, "Door close and stay", ""},
+	51:  {S1, EXIT, NO_SPEED, NO_KEY, false, false, "Exit to secret level", ""},
+	52:  {W1, EXIT, NO_SPEED, NO_KEY, false, false, "Exit level", ""},
+	53:  {W1, PLATFORM, SLOW, NO_KEY, false, false, "Perpetual platform start", "lowest and highest neighbour floor"},
+	54:  {W1, PLATFORM, NO_SPEED, NO_KEY, false, false, "Perpetual platform stop", ""},
+	55:  {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise and crush", "8 below lowest neighbour ceiling"},
+	56:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise and crush", "8 below lowest neighbour ceiling"},
+	57:  {W1, CRUSHER, NO_SPEED, NO_KEY, false, false, "Crusher stop", ""},
+	58:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24", ""},
+	59:  {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture and type", ""},
+	60:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "lowest neighbour floor"},
+	61:  {SR, DOOR, NORMAL, NO_KEY, false, false, "Door open and stay", ""},
+	62:  {SR, LIFT, FAST, NO_KEY, false, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	63:  {SR, DOOR, NORMAL, NO_KEY, false, false, "Door open, wait, close", ""},
+	64:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "lowest neighbour ceiling"},
+	65:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise and crush", "8 below lowest neighbour ceiling"},
+	66:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture", ""},
+	67:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 32, change texture", ""},
+	68:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise, change texture", "next higher neighbour floor"},
+	69:  {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	70:  {SR, FLOOR, FAST, NO_KEY, false, false, "Floor lower", "8 above highest neighbour floor"},
+	71:  {S1, FLOOR, FAST, NO_KEY, false, false, "Floor lower", "8 above highest neighbour floor"},
+	72:  {WR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "8 above floor"},
+	73:  {WR, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start", ""},
+	74:  {WR, CRUSHER, NO_SPEED, NO_KEY, false, false, "Crusher stop", ""},
+	75:  {WR, DOOR, NORMAL, NO_KEY, false, false, "Door close and stay", ""},
+	76:  {WR, DOOR, NORMAL, NO_KEY, false, false, "Door close, wait 30s, open", ""},
+	77:  {WR, CRUSHER, FAST, NO_KEY, false, false, "Crusher start", ""},
+	78:  {SR, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type, numeric model", ""},
+	79:  {WR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "35"},
+	80:  {WR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "brightest neighbour"},
+	81:  {WR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "255"},
+	82:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "lowest neighbour floor"},
+	83:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "highest neighbour floor"},
+	84:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower, change texture and type", "lowest neighbour floor"},
+	85:  {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll wall right", ""},
+	86:  {WR, DOOR, NORMAL, NO_KEY, false, false, "Door open and stay", ""},
+	87:  {WR, PLATFORM, SLOW, NO_KEY, false, false, "Perpetual platform start", "lowest and highest neighbour floor"},
+	88:  {WR, LIFT, FAST, NO_KEY, true, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	89:  {WR, PLATFORM, NO_SPEED, NO_KEY, false, false, "Perpetual platform stop", ""},
+	90:  {WR, DOOR, NORMAL, NO_KEY, false, false, "Door open, wait, close", ""},
+	91:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "lowest neighbour ceiling"},
+	92:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24", ""},
+	93:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture and type", ""},
+	94:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise and crush", "8 below lowest neighbour ceiling"},
+	95:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise, change texture", "next higher neighbour floor"},
+	96:  {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "shortest lower texture"},
+	97:  {WR, TELEPORT, NO_SPEED, NO_KEY, true, false, "Teleport", ""},
+	98:  {WR, FLOOR, FAST, NO_KEY, false, false, "Floor lower", "8 above highest neighbour floor"},
+	99:  {SR, LOCKED_DOOR, FAST, BLUE_KEY, false, false, "Door open and stay", ""},
+	100: {W1, STAIRS, TURBO, NO_KEY, false, false, "Stairs raise by 16", ""},
+	101: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "lowest neighbour ceiling"},
+	102: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "highest neighbour floor"},
+	103: {S1, DOOR, NORMAL, NO_KEY, false, false, "Door open and stay", ""},
+	104: {W1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "darkest neighbour"},
+	105: {WR, DOOR, FAST, NO_KEY, false, false, "Door open, wait, close", ""},
+	106: {WR, DOOR, FAST, NO_KEY, false, false, "Door open and stay", ""},
+	107: {WR, DOOR, FAST, NO_KEY, false, false, "Door close and stay", ""},
+	108: {W1, DOOR, FAST, NO_KEY, false, false, "Door open, wait, close", ""},
+	109: {W1, DOOR, FAST, NO_KEY, false, false, "Door open and stay", ""},
+	110: {W1, DOOR, FAST, NO_KEY, false, false, "Door close and stay", ""},
+	111: {S1, DOOR, FAST, NO_KEY, false, false, "Door open, wait, close", ""},
+	112: {S1, DOOR, FAST, NO_KEY, false, false, "Door open and stay", ""},
+	113: {S1, DOOR, FAST, NO_KEY, false, false, "Door close and stay", ""},
+	114: {SR, DOOR, FAST, NO_KEY, false, false, "Door open, wait, close", ""},
+	115: {SR, DOOR, FAST, NO_KEY, false, false, "Door open and stay", ""},
+	116: {SR, DOOR, FAST, NO_KEY, false, false, "Door close and stay", ""},
+	117: {DR, DOOR, FAST, NO_KEY, false, false, "Door open, wait, close", ""},
+	118: {D1, DOOR, FAST, NO_KEY, false, false, "Door open and stay", ""},
+	119: {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	120: {WR, LIFT, TURBO, NO_KEY, false, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	121: {W1, LIFT, TURBO, NO_KEY, false, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	122: {S1, LIFT, TURBO, NO_KEY, false, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	123: {SR, LIFT, TURBO, NO_KEY, false, false, "Lift lower, wait, raise", "lowest neighbour floor"},
+	124: {W1, EXIT, NO_SPEED, NO_KEY, false, false, "Exit to secret level", ""},
+	125: {W1, TELEPORT, NO_SPEED, NO_KEY, true, true, "Teleport monsters only", ""},
+	126: {WR, TELEPORT, NO_SPEED, NO_KEY, true, true, "Teleport monsters only", ""},
+	127: {S1, STAIRS, TURBO, NO_KEY, false, false, "Stairs raise by 16", ""},
+	128: {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	129: {WR, FLOOR, FAST, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	130: {W1, FLOOR, FAST, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	131: {S1, FLOOR, FAST, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	132: {SR, FLOOR, FAST, NO_KEY, false, false, "Floor raise", "next higher neighbour floor"},
+	133: {S1, LOCKED_DOOR, FAST, BLUE_KEY, false, false, "Door open and stay", ""},
+	134: {SR, LOCKED_DOOR, FAST, RED_KEY, false, false, "Door open and stay", ""},
+	135: {S1, LOCKED_DOOR, FAST, RED_KEY, false, false, "Door open and stay", ""},
+	136: {SR, LOCKED_DOOR, FAST, YELLOW_KEY, false, false, "Door open and stay", ""},
+	137: {S1, LOCKED_DOOR, FAST, YELLOW_KEY, false, false, "Door open and stay", ""},
+	138: {SR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "255"},
+	139: {SR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "35"},
+	140: {S1, FLOOR, NORMAL, NO_KEY, false, false, "Floor raise by 512", ""},
+	141: {W1, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start silent", ""},
+	142: {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 512", ""},
+	143: {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture", ""},
+	144: {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 32, change texture", ""},
+	145: {W1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "floor"},
+	146: {W1, DONUT, SLOW, NO_KEY, false, false, "Donut", ""},
+	147: {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 512", ""},
+	148: {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture", ""},
+	149: {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 32, change texture", ""},
+	150: {WR, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start silent", ""},
+	151: {WR, CEILING, SLOW, NO_KEY, false, false, "Ceiling raise", "highest neighbour ceiling"},
+	152: {WR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "floor"},
+	153: {W1, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type", ""},
+	154: {WR, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type", ""},
+	155: {WR, DONUT, SLOW, NO_KEY, false, false, "Donut", ""},
+	156: {WR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light start blinking", ""},
+	157: {WR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "darkest neighbour"},
+	158: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "shortest lower texture"},
+	159: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower, change texture and type", "lowest neighbour floor"},
+	160: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture and type", ""},
+	161: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24", ""},
+	162: {S1, PLATFORM, SLOW, NO_KEY, false, false, "Perpetual platform start", "lowest and highest neighbour floor"},
+	163: {S1, PLATFORM, NO_SPEED, NO_KEY, false, false, "Perpetual platform stop", ""},
+	164: {S1, CRUSHER, FAST, NO_KEY, false, false, "Crusher start", ""},
+	165: {S1, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start silent", ""},
+	166: {S1, CEILING, SLOW, NO_KEY, false, false, "Ceiling raise", "highest neighbour ceiling"},
+	167: {S1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "8 above floor"},
+	168: {S1, CRUSHER, NO_SPEED, NO_KEY, false, false, "Crusher stop", ""},
+	169: {S1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "brightest neighbour"},
+	170: {S1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "35"},
+	171: {S1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "255"},
+	172: {S1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light start blinking", ""},
+	173: {S1, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "darkest neighbour"},
+	174: {S1, TELEPORT, NO_SPEED, NO_KEY, false, false, "Teleport", ""},
+	175: {S1, DOOR, NORMAL, NO_KEY, false, false, "Door close, wait 30s, open", ""},
+	176: {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise", "shortest lower texture"},
+	177: {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower, change texture and type", "lowest neighbour floor"},
+	178: {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 512", ""},
+	179: {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24, change texture and type", ""},
+	180: {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor raise by 24", ""},
+	181: {SR, PLATFORM, SLOW, NO_KEY, false, false, "Perpetual platform start", "lowest and highest neighbour floor"},
+	182: {SR, PLATFORM, NO_SPEED, NO_KEY, false, false, "Perpetual platform stop", ""},
+	183: {SR, CRUSHER, FAST, NO_KEY, false, false, "Crusher start", ""},
+	184: {SR, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start", ""},
+	185: {SR, CRUSHER, SLOW, NO_KEY, false, false, "Crusher start silent", ""},
+	186: {SR, CEILING, SLOW, NO_KEY, false, false, "Ceiling raise", "highest neighbour ceiling"},
+	187: {SR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "8 above floor"},
+	188: {SR, CRUSHER, NO_SPEED, NO_KEY, false, false, "Crusher stop", ""},
+	189: {S1, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type", ""},
+	190: {SR, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type", ""},
+	191: {SR, DONUT, SLOW, NO_KEY, false, false, "Donut", ""},
+	192: {SR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "brightest neighbour"},
+	193: {SR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light start blinking", ""},
+	194: {SR, LIGHT, NO_SPEED, NO_KEY, false, false, "Light change", "darkest neighbour"},
+	195: {SR, TELEPORT, NO_SPEED, NO_KEY, false, false, "Teleport", ""},
+	196: {SR, DOOR, NORMAL, NO_KEY, false, false, "Door close, wait 30s, open", ""},
+	197: {G1, EXIT, NO_SPEED, NO_KEY, false, false, "Exit level", ""},
+	198: {G1, EXIT, NO_SPEED, NO_KEY, false, false, "Exit to secret level", ""},
+	199: {W1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "lowest neighbour ceiling"},
+	200: {W1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "highest neighbour floor"},
+	201: {WR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "lowest neighbour ceiling"},
+	202: {WR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "highest neighbour floor"},
+	203: {S1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "lowest neighbour ceiling"},
+	204: {S1, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "highest neighbour floor"},
+	205: {SR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "lowest neighbour ceiling"},
+	206: {SR, CEILING, SLOW, NO_KEY, false, false, "Ceiling lower", "highest neighbour floor"},
+	207: {W1, TELEPORT, NO_SPEED, NO_KEY, true, false, "Silent teleport", ""},
+	208: {WR, TELEPORT, NO_SPEED, NO_KEY, true, false, "Silent teleport", ""},
+	209: {S1, TELEPORT, NO_SPEED, NO_KEY, false, false, "Silent teleport", ""},
+	210: {SR, TELEPORT, NO_SPEED, NO_KEY, false, false, "Silent teleport", ""},
+	211: {SR, PLATFORM, INSTANT, NO_KEY, false, false, "Toggle floor between floor and ceiling", ""},
+	212: {WR, PLATFORM, INSTANT, NO_KEY, false, false, "Toggle floor between floor and ceiling", ""},
+	213: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Transfer floor light level", ""},
+	214: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll ceiling, accelerating", ""},
+	215: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll floor, accelerating", ""},
+	216: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Carry objects, accelerating", ""},
+	217: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll floor and carry objects, accelerating", ""},
+	218: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll wall, accelerating", ""},
+	219: {W1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "next lower neighbour floor"},
+	220: {WR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "next lower neighbour floor"},
+	221: {S1, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "next lower neighbour floor"},
+	222: {SR, FLOOR, SLOW, NO_KEY, false, false, "Floor lower", "next lower neighbour floor"},
+	223: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Set friction", ""},
+	224: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Set wind", ""},
+	225: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Set current", ""},
+	226: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Set point wind or current", ""},
+	227: {W1, ELEVATOR, FAST, NO_KEY, false, false, "Elevator raise", "next higher floor"},
+	228: {WR, ELEVATOR, FAST, NO_KEY, false, false, "Elevator raise", "next higher floor"},
+	229: {S1, ELEVATOR, FAST, NO_KEY, false, false, "Elevator raise", "next higher floor"},
+	230: {SR, ELEVATOR, FAST, NO_KEY, false, false, "Elevator raise", "next higher floor"},
+	231: {W1, ELEVATOR, FAST, NO_KEY, false, false, "Elevator lower", "next lower floor"},
+	232: {WR, ELEVATOR, FAST, NO_KEY, false, false, "Elevator lower", "next lower floor"},
+	233: {S1, ELEVATOR, FAST, NO_KEY, false, false, "Elevator lower", "next lower floor"},
+	234: {SR, ELEVATOR, FAST, NO_KEY, false, false, "Elevator lower", "next lower floor"},
+	235: {W1, ELEVATOR, FAST, NO_KEY, false, false, "Elevator move", "activating sector's floor"},
+	236: {WR, ELEVATOR, FAST, NO_KEY, false, false, "Elevator move", "activating sector's floor"},
+	237: {S1, ELEVATOR, FAST, NO_KEY, false, false, "Elevator move", "activating sector's floor"},
+	238: {SR, ELEVATOR, FAST, NO_KEY, false, false, "Elevator move", "activating sector's floor"},
+	239: {W1, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type, numeric model", ""},
+	240: {WR, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type, numeric model", ""},
+	241: {S1, TEXTURE_CHANGE, NO_SPEED, NO_KEY, false, false, "Change floor texture and type, numeric model", ""},
+	242: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Create fake floor and ceiling", ""},
+	243: {W1, TELEPORT, NO_SPEED, NO_KEY, true, false, "Silent line teleport", ""},
+	244: {WR, TELEPORT, NO_SPEED, NO_KEY, true, false, "Silent line teleport", ""},
+	245: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll ceiling by displacement", ""},
+	246: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll floor by displacement", ""},
+	247: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Carry objects by displacement", ""},
+	248: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll floor and carry objects by displacement", ""},
+	249: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll wall by displacement", ""},
+	250: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll ceiling", ""},
+	251: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll floor", ""},
+	252: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Carry objects", ""},
+	253: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll floor and carry objects", ""},
+	254: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll wall parallel to linedef", ""},
+	255: {NO_TRIGGER, SCROLL, NO_SPEED, NO_KEY, false, false, "Scroll wall by sidedef offsets", ""},
+	256: {WR, STAIRS, SLOW, NO_KEY, false, false, "Stairs raise by 8", ""},
+	257: {WR, STAIRS, TURBO, NO_KEY, false, false, "Stairs raise by 16", ""},
+	258: {SR, STAIRS, SLOW, NO_KEY, false, false, "Stairs raise by 8", ""},
+	259: {SR, STAIRS, TURBO, NO_KEY, false, false, "Stairs raise by 16", ""},
+	260: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Translucent linedef", ""},
+	261: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Transfer ceiling light level", ""},
+	262: {W1, TELEPORT, NO_SPEED, NO_KEY, true, false, "Silent line teleport, reversed", ""},
+	263: {WR, TELEPORT, NO_SPEED, NO_KEY, true, false, "Silent line teleport, reversed", ""},
+	264: {W1, TELEPORT, NO_SPEED, NO_KEY, true, true, "Silent line teleport monsters only, reversed", ""},
+	265: {WR, TELEPORT, NO_SPEED, NO_KEY, true, true, "Silent line teleport monsters only, reversed", ""},
+	266: {W1, TELEPORT, NO_SPEED, NO_KEY, true, true, "Silent line teleport monsters only", ""},
+	267: {WR, TELEPORT, NO_SPEED, NO_KEY, true, true, "Silent line teleport monsters only", ""},
+	268: {W1, TELEPORT, NO_SPEED, NO_KEY, true, true, "Silent teleport monsters only", ""},
+	269: {WR, TELEPORT, NO_SPEED, NO_KEY, true, true, "Silent teleport monsters only", ""},
+	271: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Transfer sky texture", ""},
+	272: {NO_TRIGGER, TRANSFER, NO_SPEED, NO_KEY, false, false, "Transfer sky texture, flipped", ""},
+}
+
+// generalizedKeys lists what the key field of a generalized locked door means, first
+// when keycards and skull keys are distinct and then when they are not.
+var generalizedKeys = [2][8]Key{
+	{ANY_KEY, RED_CARD, BLUE_CARD, YELLOW_CARD, RED_SKULL, BLUE_SKULL, YELLOW_SKULL, ALL_SIX_KEYS},
+	{ANY_KEY, RED_KEY, BLUE_KEY, YELLOW_KEY, RED_KEY, BLUE_KEY, YELLOW_KEY, ALL_THREE_KEYS},
+}
+
+var floorTargets = []string{"highest neighbour floor", "lowest neighbour floor", "next neighbour floor", "lowest neighbour ceiling", "ceiling", "shortest lower texture", "24 units", "32 units"}
+var ceilingTargets = []string{"highest neighbour ceiling", "lowest neighbour ceiling", "next neighbour ceiling", "highest neighbour floor", "floor", "shortest upper texture", "24 units", "32 units"}
+var liftTargets = []string{"lowest neighbour floor", "next lower neighbour floor", "lowest neighbour ceiling", "lowest and highest neighbour floor"}
+var doorKinds = []string{"Door open, wait, close", "Door open and stay", "Door close, wait, open", "Door close and stay"}
+var doorDelays = []int{1, 4, 9, 30}
+var liftDelays = []int{1, 3, 5, 10}
+var stairSteps = []int{4, 8, 16, 24}
+var changeKinds = []string{"", ", change texture and zero type", ", change texture", ", change texture and type"}
+
+// decodeGeneralized works out a Boom generalized special from the bit fields
+// packed into its type.
+func decodeGeneralized(t uint16) (Special, bool) {
+	s := Special{
+		Type:        t,
+		Trigger:     Trigger(t&0x7) + W1,
+		Speed:       Speed((t&0x18)>>3) + SLOW,
+		Generalized: true,
+	}
+	switch {
+	case t >= 0x4000:
+		s.Category = FLOOR
+		targets := floorTargets
+		if t < 0x6000 {
+			s.Category = CEILING
+			targets = ceilingTargets
+		}
+		change := (t & 0xC00) >> 10
+		if change == 0 {
+			s.Monsters = t&0x20 != 0
+		}
+		direction := "lower"
+		if t&0x40 != 0 {
+			direction = "raise"
+		}
+		s.Description = fmt.Sprintf("%s %s%s", s.Category, direction, changeKinds[change])
+		if t&0x1000 != 0 {
+			s.Description += ", crush"
+		}
+		s.Target = targets[(t&0x380)>>7]
+	case t >= 0x3C00:
+		s.Category = DOOR
+		s.Monsters = t&0x80 != 0
+		kind := (t & 0x60) >> 5
+		s.Description = doorKinds[kind]
+		if kind == 0 || kind == 2 {
+			s.Description = fmt.Sprintf("%s (%ds)", s.Description, doorDelays[(t&0x300)>>8])
+		}
+	case t >= 0x3800:
+		s.Category = LOCKED_DOOR
+		s.Description = doorKinds[(t&0x20)>>5]
+		same := 0
+		if t&0x200 != 0 {
+			same = 1
+		}
+		s.Key = generalizedKeys[same][(t&0x1C0)>>6]
+	case t >= 0x3400:
+		s.Category = LIFT
+		s.Monsters = t&0x20 != 0
+		target := (t & 0x300) >> 8
+		if target == 3 {
+			s.Category = PLATFORM
+			s.Description = "Perpetual platform start"
+		} else {
+			s.Description = fmt.Sprintf("Lift lower, wait %ds, raise", liftDelays[(t&0xC0)>>6])
+		}
+		s.Target = liftTargets[target]
+	case t >= 0x3000:
+		s.Category = STAIRS
+		s.Monsters = t&0x20 != 0
+		direction := "lower"
+		if t&0x100 != 0 {
+			direction = "raise"
+		}
+		s.Description = fmt.Sprintf("Stairs %s by %d", direction, stairSteps[(t&0xC0)>>6])
+		if t&0x200 != 0 {
+			s.Description += ", ignoring texture"
+		}
+	case t >= 0x2F80:
+		s.Category = CRUSHER
+		s.Monsters = t&0x20 != 0
+		s.Description = "Crusher start"
+		if t&0x40 != 0 {
+			s.Description += " silent"
+		}
+	default:
+		return Special{}, false
+	}
+	return s, true
+}
+
+// DecodeSpecial describes the linedef special with the given type, which may
+// be a Doom, Boom or MBF special or a Boom generalized one. Types above
+// 0x7FFF are not Boom specials.
+func DecodeSpecial(t uint16) (Special, bool) {
+	if t > 0x7FFF {
+		return Special{}, false
+	}
+	if t >= 0x2F80 {
+		return decodeGeneralized(t)
+	}
+	def, ok := specials[t]
+	if !ok {
+		return Special{}, false
+	}
+	return Special{
+		Type:         t,
+		Trigger:      def.trigger,
+		Category:     def.category,
+		Description:  def.description,
+		Speed:        def.speed,
+		Key:          def.key,
+		Target:       def.target,
+		Monsters:     def.monsters,
+		MonstersOnly: def.monstersOnly,
+	}, true
+}
+
+// Special describes the linedef's special, if it has one that is known.
+func (l *LineDef) Special() (Special, bool) {
+	if l.SpecialType == 0 {
+		return Special{}, false
+	}
+	return DecodeSpecial(l.SpecialType)
+}
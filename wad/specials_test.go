@@ -0,0 +1,67 @@
+package wad
+
+import "testing"
+
+// The generalized types below are put together from the bit fields laid out
+// in boomref.txt.
+func TestDecodeGeneralized(t *testing.T) {
+	tests := []struct {
+		t    uint16
+		want Special
+	}{
+		{0x6000, Special{Trigger: W1, Category: FLOOR, Speed: SLOW, Description: "Floor lower", Target: "highest neighbour floor"}},
+		{0x4000, Special{Trigger: W1, Category: CEILING, Speed: SLOW, Description: "Ceiling lower", Target: "highest neighbour ceiling"}},
+		{0x71D3, Special{Trigger: SR, Category: FLOOR, Speed: FAST, Description: "Floor raise, crush", Target: "lowest neighbour ceiling"}},
+		{0x6862, Special{Trigger: S1, Category: FLOOR, Speed: SLOW, Description: "Floor raise, change texture", Target: "highest neighbour floor"}},
+		{0x6021, Special{Trigger: WR, Category: FLOOR, Speed: SLOW, Description: "Floor lower", Target: "highest neighbour floor", Monsters: true}},
+		{0x4E0C, Special{Trigger: G1, Category: CEILING, Speed: NORMAL, Description: "Ceiling lower, change texture and type", Target: "floor"}},
+		{0x3C01, Special{Trigger: WR, Category: DOOR, Speed: SLOW, Description: "Door open, wait, close (1s)"}},
+		{0x3D8F, Special{Trigger: DR, Category: DOOR, Speed: NORMAL, Description: "Door open, wait, close (4s)", Monsters: true}},
+		{0x3C7E, Special{Trigger: D1, Category: DOOR, Speed: TURBO, Description: "Door close and stay"}},
+		{0x3966, Special{Trigger: D1, Category: LOCKED_DOOR, Speed: SLOW, Description: "Door open and stay", Key: BLUE_SKULL}},
+		{0x3B66, Special{Trigger: D1, Category: LOCKED_DOOR, Speed: SLOW, Description: "Door open and stay", Key: BLUE_KEY}},
+		{0x39CF, Special{Trigger: DR, Category: LOCKED_DOOR, Speed: NORMAL, Description: "Door open, wait, close", Key: ALL_SIX_KEYS}},
+		{0x3543, Special{Trigger: SR, Category: LIFT, Speed: SLOW, Description: "Lift lower, wait 3s, raise", Target: "next lower neighbour floor"}},
+		{0x3700, Special{Trigger: W1, Category: PLATFORM, Speed: SLOW, Description: "Perpetual platform start", Target: "lowest and highest neighbour floor"}},
+		{0x3182, Special{Trigger: S1, Category: STAIRS, Speed: SLOW, Description: "Stairs raise by 16"}},
+		{0x32E9, Special{Trigger: WR, Category: STAIRS, Speed: NORMAL, Description: "Stairs lower by 24, ignoring texture", Monsters: true}},
+		{0x2FC1, Special{Trigger: WR, Category: CRUSHER, Speed: SLOW, Description: "Crusher start silent"}},
+	}
+	for _, test := range tests {
+		got, ok := DecodeSpecial(test.t)
+		if !ok {
+			t.Errorf("DecodeSpecial(%#x) was not decoded", test.t)
+			continue
+		}
+		test.want.Type = test.t
+		test.want.Generalized = true
+		if got != test.want {
+			t.Errorf("DecodeSpecial(%#x) = %+v, want %+v", test.t, got, test.want)
+		}
+	}
+}
+
+func TestDecodeSpecialUnknown(t *testing.T) {
+	for _, typ := range []uint16{270, 273, 0x2F7F, 0x8000, 0xC000, 0xFFFF} {
+		if s, ok := DecodeSpecial(typ); ok {
+			t.Errorf("DecodeSpecial(%#x) = %+v, want no special", typ, s)
+		}
+	}
+}
+
+func TestDecodeSpecialBoomScrollers(t *testing.T) {
+	for typ, want := range map[uint16]string{48: "Scroll wall left", 78: "Change floor texture and type, numeric model", 85: "Scroll wall right"} {
+		s, ok := DecodeSpecial(typ)
+		if !ok || s.Description != want {
+			t.Errorf("DecodeSpecial(%d) = %q, %v, want %q", typ, s.Description, ok, want)
+		}
+	}
+}
+
+func TestDecodeSpecialMonstersOnly(t *testing.T) {
+	for typ, want := range map[uint16]bool{39: false, 97: false, 125: true, 126: true, 207: false, 266: true, 269: true} {
+		if s, _ := DecodeSpecial(typ); s.MonstersOnly != want {
+			t.Errorf("DecodeSpecial(%d).MonstersOnly = %v, want %v", typ, s.MonstersOnly, want)
+		}
+	}
+}
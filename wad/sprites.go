@@ -0,0 +1,32 @@
+package wad
+
+import (
+	"strings"
+)
+
+// SpriteName returns the four character sprite name used to draw the thing,
+// or "" if it is not known.
+func (t *Thing) SpriteName() string {
+	return thingInfo[t.ThingType].Sprite
+}
+
+// SpriteFrame returns the lump holding the first frame of the named sprite,
+// preferring the rotation that faces the viewer, or nil if there is none.
+func (d *Directory) SpriteFrame(sprite string) *LumpPtr {
+	var facing, other *LumpPtr
+	for _, l := range d.Namespace("S") {
+		name := l.Name()
+		if !strings.HasPrefix(name, sprite+"A") || len(name) < 6 {
+			continue
+		}
+		if name[5] == '0' || name[5] == '1' {
+			facing = l
+		} else {
+			other = l
+		}
+	}
+	if facing != nil {
+		return facing
+	}
+	return other
+}
@@ -1,10 +1,14 @@
+// Package wad reads and writes Doom WAD files and the maps inside them.
 package wad
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
+	"image/color"
 	"io"
-	"os"
+
+	"github.com/macripps/wad2svg/logging"
 )
 
 type LineDefFlag uint16
@@ -43,17 +47,38 @@ type LineDef struct {
 	LeftSideDef  uint16
 }
 
+func (l *LineDef) isCategory(categories ...SpecialCategory) bool {
+	special, ok := l.Special()
+	if !ok {
+		return false
+	}
+	for _, c := range categories {
+		if special.Category == c {
+			return true
+		}
+	}
+	return false
+}
 func (l *LineDef) IsDoor() bool {
-	return l.SpecialType == 1 || l.SpecialType == 2 || l.SpecialType == 3 || l.SpecialType == 4 || l.SpecialType == 16
+	return l.isCategory(DOOR, LOCKED_DOOR)
+}
+func (l *LineDef) IsLockedDoor() bool {
+	return l.isCategory(LOCKED_DOOR)
+}
+
+// RequiredKey returns the key needed to activate the linedef's special.
+func (l *LineDef) RequiredKey() Key {
+	special, _ := l.Special()
+	return special.Key
 }
 func (l *LineDef) IsTeleporter() bool {
-	return l.SpecialType == 39 || l.SpecialType == 97 || l.SpecialType == 125 || l.SpecialType == 126 || l.SpecialType == 174 || l.SpecialType == 195 || l.SpecialType == 207 || l.SpecialType == 208 || l.SpecialType == 209 || l.SpecialType == 210 || l.SpecialType == 243 || l.SpecialType == 244 || l.SpecialType == 262 || l.SpecialType == 263 || l.SpecialType == 264 || l.SpecialType == 265 || l.SpecialType == 266 || l.SpecialType == 267 || l.SpecialType == 268 || l.SpecialType == 269
+	return l.isCategory(TELEPORT)
 }
 func (l *LineDef) IsLift() bool {
-	return l.SpecialType == 10 || l.SpecialType == 21 || l.SpecialType == 62 || l.SpecialType == 88 || l.SpecialType == 120 || l.SpecialType == 121 || l.SpecialType == 123
+	return l.isCategory(LIFT)
 }
 func (l *LineDef) IsExit() bool {
-	return l.SpecialType == 11 || l.SpecialType == 51 || l.SpecialType == 52 || l.SpecialType == 124 || l.SpecialType == 197 || l.SpecialType == 198
+	return l.isCategory(EXIT)
 }
 func (l *LineDef) IsSecret() bool {
 	return l.Flags&uint16(SECRET) == uint16(SECRET)
@@ -70,6 +95,8 @@ func (l LineDef) Flip() LineDef {
 	}
 }
 
+// SideDef is one side of a linedef. Texture names are as written in the map,
+// without their NUL padding; the engine compares them without regard to case.
 type SideDef struct {
 	XOffset           int16
 	YOffset           int16
@@ -77,6 +104,9 @@ type SideDef struct {
 	LowerTextureName  string
 	MiddleTextureName string
 	SectorNumber      uint16
+	// rawNames keeps the texture names as read, so that they are written
+	// back byte for byte while unchanged.
+	rawNames [3][8]byte
 }
 
 type Vertex struct {
@@ -84,6 +114,8 @@ type Vertex struct {
 	Y int16
 }
 
+// Sector is an area of the map. Flat names are as written in the map,
+// without their NUL padding.
 type Sector struct {
 	FloorHeight    int16
 	CeilingHeight  int16
@@ -92,6 +124,8 @@ type Sector struct {
 	LightLevel     uint16
 	SectorType     uint16
 	TagNumber      uint16
+	// rawNames keeps the flat names as read, as for SideDef.
+	rawNames [2][8]byte
 }
 
 func (s *Sector) isSecret() bool {
@@ -102,15 +136,54 @@ func (s *Sector) isDamage() bool {
 	return s.SectorType == 4 || s.SectorType == 5 || s.SectorType == 7 || s.SectorType == 16
 }
 
-var sectorFill = []string{"white", "white", "white", "white", "red", "red", "unused", "red", "white", "aqua", "green", "purple", "white", "white", "green", "unused", "red", "white"}
-var sectorStroke = []string{"black", "black", "black", "black", "red", "red", "unused", "red", "black", "aqua", "green", "purple", "black", "black", "green", "unused", "red", "black"}
-var sectorOpacity = []string{"1.0", "1.0", "1.0", "1.0", "0.2", "0.1", "unused", "0.05", "1.0", "0.5", "1.0", "1.0", "1.0", "1.0", "1.0", "unused", "0.2", "1.0"}
+type LightEffect int
 
-func (s *Sector) ToSvgAttributeString() string {
+const (
+	NO_LIGHT_EFFECT LightEffect = iota
+	LIGHT_FLICKER
+	LIGHT_STROBE
+	LIGHT_GLOW
+)
+
+// LightEffect returns the lighting effect of the sector's special, ignoring
+// the Boom generalized bits above the first five.
+func (s *Sector) LightEffect() LightEffect {
+	switch s.SectorType & 31 {
+	case 1, 17:
+		return LIGHT_FLICKER
+	case 2, 3, 4, 12, 13:
+		return LIGHT_STROBE
+	case 8:
+		return LIGHT_GLOW
+	}
+	return NO_LIGHT_EFFECT
+}
+
+var (
+	white  = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	black  = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	red    = color.RGBA{0xff, 0x00, 0x00, 0xff}
+	green  = color.RGBA{0x00, 0x80, 0x00, 0xff}
+	aqua   = color.RGBA{0x00, 0xff, 0xff, 0xff}
+	purple = color.RGBA{0x80, 0x00, 0x80, 0xff}
+)
+
+var sectorFill = []color.RGBA{white, white, white, white, red, red, white, red, white, aqua, green, purple, white, white, green, white, red, white}
+var sectorStroke = []color.RGBA{black, black, black, black, red, red, black, red, black, aqua, green, purple, black, black, green, black, red, black}
+var sectorOpacity = []float64{1, 1, 1, 1, 0.2, 0.1, 1, 0.05, 1, 0.5, 1, 1, 1, 1, 1, 1, 0.2, 1}
+
+// Colours returns how the sector is drawn on a map by its type: the colour
+// it is filled with, the opacity of the fill and the colour of its outline.
+func (s *Sector) Colours() (color.RGBA, float64, color.RGBA) {
 	if int(s.SectorType) < len(sectorFill) {
-		return fmt.Sprintf("fill=\"%s\" stroke=\"%s\" fill-opacity=\"%s\" stroke-width=\"1\"", sectorFill[s.SectorType], sectorStroke[s.SectorType], sectorOpacity[s.SectorType])
+		return sectorFill[s.SectorType], sectorOpacity[s.SectorType], sectorStroke[s.SectorType]
 	}
-	return "fill=\"white\" stroke=\"black\" fill-opacity=\"1.0\" stroke-width=\"1\""
+	return white, 1, black
+}
+
+func (s *Sector) ToSvgAttributeString() string {
+	fill, opacity, stroke := s.Colours()
+	return fmt.Sprintf("fill=\"rgb(%d,%d,%d)\" stroke=\"rgb(%d,%d,%d)\" fill-opacity=\"%g\" stroke-width=\"1\"", fill.R, fill.G, fill.B, stroke.R, stroke.G, stroke.B, opacity)
 }
 
 type Thing struct {
@@ -121,71 +194,173 @@ type Thing struct {
 	Flags     uint16
 }
 
+func (t *Thing) IsAmmo() bool {
+	return t.ThingType == 17 || t.ThingType == 2007 || t.ThingType == 2008 || t.ThingType == 2010 || t.ThingType == 2046 || t.ThingType == 2047 || t.ThingType == 2048 || t.ThingType == 2049
+}
+func (t *Thing) IsArtifact() bool {
+	return t.ThingType == 83 || t.ThingType == 2013 || t.ThingType == 2014 || t.ThingType == 2015 || t.ThingType == 2022 || t.ThingType == 2023 || t.ThingType == 2024 || t.ThingType == 2026 || t.ThingType == 2045
+}
+func (t *Thing) IsKey() bool {
+	return t.ThingType == 5 || t.ThingType == 6 || t.ThingType == 13 || t.ThingType == 38 || t.ThingType == 39 || t.ThingType == 40
+}
+
+// Key returns which key the thing is, or NO_KEY if it is not one.
+func (t *Thing) Key() Key {
+	switch t.ThingType {
+	case 5:
+		return BLUE_CARD
+	case 6:
+		return YELLOW_CARD
+	case 13:
+		return RED_CARD
+	case 38:
+		return RED_SKULL
+	case 39:
+		return YELLOW_SKULL
+	case 40:
+		return BLUE_SKULL
+	}
+	return NO_KEY
+}
+func (t *Thing) IsMonster() bool {
+	return t.ThingType == 7 || t.ThingType == 9 || t.ThingType == 16 || t.ThingType == 58 || t.ThingType == 64 || t.ThingType == 65 || t.ThingType == 66 || t.ThingType == 67 || t.ThingType == 68 || t.ThingType == 69 || t.ThingType == 71 || t.ThingType == 72 || t.ThingType == 84 || t.ThingType == 3001 || t.ThingType == 3002 || t.ThingType == 3003 || t.ThingType == 3004 || t.ThingType == 3005 || t.ThingType == 3006
+}
+func (t *Thing) IsPowerup() bool {
+	return t.ThingType == 8 || t.ThingType == 2011 || t.ThingType == 2012 || t.ThingType == 2018 || t.ThingType == 2019 || t.ThingType == 2025
+}
+func (t *Thing) IsWeapon() bool {
+	return t.ThingType == 82 || t.ThingType == 2001 || t.ThingType == 2002 || t.ThingType == 2003 || t.ThingType == 2004 || t.ThingType == 2005 || t.ThingType == 2006
+}
+
+// BlockMap holds the header of the BLOCKMAP lump. Unlike Vertex and Thing,
+// the origin is in map coordinates, with Y increasing northwards.
+type BlockMap struct {
+	OriginX int16
+	OriginY int16
+	Columns uint16
+	Rows    uint16
+}
+
 type Map struct {
 	LineDefs []LineDef
 	SideDefs []SideDef
 	Vertexes []Vertex
 	Sectors  []Sector
 	Things   []Thing
+	BlockMap *BlockMap
+}
+
+// LineDefSectors returns the numbers of the sectors on the right and left of
+// l, or -1 for a side without a sidedef.
+func (m *Map) LineDefSectors(l LineDef) (int, int) {
+	right, left := -1, -1
+	if int(l.RightSideDef) < len(m.SideDefs) {
+		right = int(m.SideDefs[l.RightSideDef].SectorNumber)
+	}
+	if int(l.LeftSideDef) < len(m.SideDefs) {
+		left = int(m.SideDefs[l.LeftSideDef].SectorNumber)
+	}
+	return right, left
+}
+
+// SectorBoundary returns the linedefs separating a sector from the void or
+// from other sectors. Linedefs with the sector on both sides are left out.
+func (m *Map) SectorBoundary(sector int) []LineDef {
+	boundary := make([]LineDef, 0)
+	for _, l := range m.LineDefs {
+		right, left := m.LineDefSectors(l)
+		if (right == sector) != (left == sector) {
+			boundary = append(boundary, l)
+		}
+	}
+	return boundary
+}
+
+// SectorAt returns the sector containing the point, in the same coordinates
+// as the map's vertexes, or -1 if it is outside every sector.
+func (m *Map) SectorAt(x int16, y int16) int {
+	inside := make(map[int]bool)
+	px, py := float64(x)+0.5, float64(y)+0.5
+	for _, l := range m.LineDefs {
+		if int(l.Start) >= len(m.Vertexes) || int(l.End) >= len(m.Vertexes) {
+			continue
+		}
+		right, left := m.LineDefSectors(l)
+		if right == left {
+			continue
+		}
+		start, end := m.Vertexes[l.Start], m.Vertexes[l.End]
+		x1, y1, x2, y2 := float64(start.X), float64(start.Y), float64(end.X), float64(end.Y)
+		if (y1 > py) != (y2 > py) && px < (x2-x1)*(py-y1)/(y2-y1)+x1 {
+			for _, s := range []int{right, left} {
+				if s >= 0 {
+					inside[s] = !inside[s]
+				}
+			}
+		}
+	}
+	for s := range m.Sectors {
+		if inside[s] {
+			return s
+		}
+	}
+	return -1
 }
 
-func (m *Map) parseThings(r io.ReaderAt, size uint32, offset int64) {
-	numThings := size / 10
+func (m *Map) parseThings(data []byte, log *logging.Logger) {
+	numThings := len(data) / 10
 	m.Things = make([]Thing, 0, numThings)
-	var t Thing
-	fmt.Fprintf(os.Stderr, "Reading %d things\n", numThings)
-	for numThings > 0 {
-		t, offset = ReadThingFrom(r, offset)
-		m.Things = append(m.Things, t)
-		numThings--
+	log.Debug("Reading things", "count", numThings)
+	for i := 0; i < numThings; i++ {
+		m.Things = append(m.Things, decodeThing(data[i*10:i*10+10]))
 	}
 }
 
-func (m *Map) parseLineDefs(r io.ReaderAt, size uint32, offset int64) {
-	numLineDefs := size / 14
+func (m *Map) parseLineDefs(data []byte, log *logging.Logger) {
+	numLineDefs := len(data) / 14
 	m.LineDefs = make([]LineDef, 0, numLineDefs)
-	var l LineDef
-	fmt.Fprintf(os.Stderr, "Reading %d linedefs\n", numLineDefs)
-	for numLineDefs > 0 {
-		l, offset = ReadLineDefFrom(r, offset)
-		m.LineDefs = append(m.LineDefs, l)
-		numLineDefs--
+	log.Debug("Reading linedefs", "count", numLineDefs)
+	for i := 0; i < numLineDefs; i++ {
+		m.LineDefs = append(m.LineDefs, decodeLineDef(data[i*14:i*14+14]))
 	}
 }
 
-func (m *Map) parseSideDefs(r io.ReaderAt, size uint32, offset int64) {
-	numSideDefs := size / 30
+func (m *Map) parseSideDefs(data []byte, log *logging.Logger) {
+	numSideDefs := len(data) / 30
 	m.SideDefs = make([]SideDef, 0, numSideDefs)
-	var s SideDef
-	fmt.Fprintf(os.Stderr, "Reading %d sidedefs\n", numSideDefs)
-	for numSideDefs > 0 {
-		s, offset = ReadSideDefFrom(r, offset)
-		m.SideDefs = append(m.SideDefs, s)
-		numSideDefs--
+	log.Debug("Reading sidedefs", "count", numSideDefs)
+	for i := 0; i < numSideDefs; i++ {
+		m.SideDefs = append(m.SideDefs, decodeSideDef(data[i*30:i*30+30]))
 	}
 }
 
-func (m *Map) parseVertexes(r io.ReaderAt, size uint32, offset int64) {
-	numVertexes := size / 4
+func (m *Map) parseVertexes(data []byte, log *logging.Logger) {
+	numVertexes := len(data) / 4
 	m.Vertexes = make([]Vertex, 0, numVertexes)
-	fmt.Fprintf(os.Stderr, "Reading %d vertexes\n", numVertexes)
-	var v Vertex
-	for numVertexes > 0 {
-		v, offset = ReadVertexFrom(r, offset)
-		m.Vertexes = append(m.Vertexes, v)
-		numVertexes--
+	log.Debug("Reading vertexes", "count", numVertexes)
+	for i := 0; i < numVertexes; i++ {
+		m.Vertexes = append(m.Vertexes, decodeVertex(data[i*4:i*4+4]))
+	}
+}
+
+func (m *Map) parseBlockMap(data []byte, log *logging.Logger) {
+	if len(data) < 8 {
+		return
+	}
+	m.BlockMap = &BlockMap{
+		OriginX: int16(binary.LittleEndian.Uint16(data[0:2])),
+		OriginY: int16(binary.LittleEndian.Uint16(data[2:4])),
+		Columns: binary.LittleEndian.Uint16(data[4:6]),
+		Rows:    binary.LittleEndian.Uint16(data[6:8]),
 	}
 }
 
-func (m *Map) parseSectors(r io.ReaderAt, size uint32, offset int64) {
-	numSectors := size / 26
+func (m *Map) parseSectors(data []byte, log *logging.Logger) {
+	numSectors := len(data) / 26
 	m.Sectors = make([]Sector, 0, numSectors)
-	fmt.Fprintf(os.Stderr, "Reading %d sectors\n", numSectors)
-	var s Sector
-	for numSectors > 0 {
-		s, offset = ReadSectorFrom(r, offset)
-		m.Sectors = append(m.Sectors, s)
-		numSectors--
+	log.Debug("Reading sectors", "count", numSectors)
+	for i := 0; i < numSectors; i++ {
+		m.Sectors = append(m.Sectors, decodeSector(data[i*26:i*26+26]))
 	}
 }
 
@@ -211,61 +386,27 @@ type LumpPtr struct {
 	name   string
 }
 
-func readLump(r io.ReaderAt, offset int64) (*LumpPtr, int64) {
-	var buffer = make([]byte, 8)
-	var lump = &LumpPtr{}
-	r.ReadAt(buffer, offset)
-	lump.offset = binary.LittleEndian.Uint32(buffer[0:4])
-	lump.size = binary.LittleEndian.Uint32(buffer[4:8])
-	r.ReadAt(buffer, offset+8)
-	lump.name = string(buffer)
-	return lump, offset + 16
-}
-
+// ReadFrom reads the named map from the WAD in r. The map is left empty if
+// it cannot be read.
+//
+// Deprecated: use LoadMap, which reports what went wrong.
 func (m *Map) ReadFrom(r io.ReaderAt, mapName string) {
-	var buffer = make([]byte, 4)
-	r.ReadAt(buffer, 4)
-	numLumps := binary.LittleEndian.Uint32(buffer)
-	r.ReadAt(buffer, 8)
-	var infoTableOffset = binary.LittleEndian.Uint32(buffer)
-	m.readMapFromInfoTable(r, mapName, int(numLumps), int64(infoTableOffset))
-}
-
-func (m *Map) readMapFromInfoTable(r io.ReaderAt, mapName string, numLumps int, offset int64) {
-	mapNamePadded := (mapName + "\x00\x00\x00\x00\x00\x00\x00\x00")[:8]
-	var lump *LumpPtr
-	found := false
-	for numLumps > 0 {
-		lump, offset = readLump(r, offset)
-		if lump.name == mapNamePadded {
-			fmt.Fprintf(os.Stderr, "Found map %s\n", lump.name)
-			found = true
-		}
-		if found && lump.name == "THINGS\x00\x00" {
-			m.parseThings(r, lump.size, int64(lump.offset))
-		}
-		if found && lump.name == "LINEDEFS" {
-			m.parseLineDefs(r, lump.size, int64(lump.offset))
-		}
-		if found && lump.name == "SIDEDEFS" {
-			m.parseSideDefs(r, lump.size, int64(lump.offset))
-		}
-		if found && lump.name == "VERTEXES" {
-			m.parseVertexes(r, lump.size, int64(lump.offset))
-		}
-		if found && lump.name == "SECTORS\x00" {
-			m.parseSectors(r, lump.size, int64(lump.offset))
-			break
-		}
-		numLumps--
+	loaded, err := LoadMap(context.Background(), r, mapName)
+	if err != nil {
+		logging.Error("Unable to read map", "map", mapName, "error", err)
+		return
 	}
+	*m = *loaded
 }
 
-var linedef = make([]byte, 14)
-
 func ReadLineDefFrom(r io.ReaderAt, offset int64) (LineDef, int64) {
+	linedef := make([]byte, 14)
 	r.ReadAt(linedef, offset)
-	l := LineDef{
+	return decodeLineDef(linedef), offset + 14
+}
+
+func decodeLineDef(linedef []byte) LineDef {
+	return LineDef{
 		Start:        binary.LittleEndian.Uint16(linedef[0:2]),
 		End:          binary.LittleEndian.Uint16(linedef[2:4]),
 		Flags:        binary.LittleEndian.Uint16(linedef[4:6]),
@@ -274,59 +415,75 @@ func ReadLineDefFrom(r io.ReaderAt, offset int64) (LineDef, int64) {
 		RightSideDef: binary.LittleEndian.Uint16(linedef[10:12]),
 		LeftSideDef:  binary.LittleEndian.Uint16(linedef[12:14]),
 	}
-
-	return l, offset + 14
 }
 
-var sidedef = make([]byte, 30)
-
 func ReadSideDefFrom(r io.ReaderAt, offset int64) (SideDef, int64) {
+	sidedef := make([]byte, 30)
 	r.ReadAt(sidedef, offset)
-	l := SideDef{
-		SectorNumber: binary.LittleEndian.Uint16(sidedef[28:30]),
-	}
-
-	return l, offset + 30
+	return decodeSideDef(sidedef), offset + 30
 }
 
-var vertex = make([]byte, 4)
+func decodeSideDef(sidedef []byte) SideDef {
+	s := SideDef{
+		XOffset:           int16(binary.LittleEndian.Uint16(sidedef[0:2])),
+		YOffset:           int16(binary.LittleEndian.Uint16(sidedef[2:4])),
+		UpperTextureName:  trimNUL(sidedef[4:12]),
+		LowerTextureName:  trimNUL(sidedef[12:20]),
+		MiddleTextureName: trimNUL(sidedef[20:28]),
+		SectorNumber:      binary.LittleEndian.Uint16(sidedef[28:30]),
+	}
+	copy(s.rawNames[0][:], sidedef[4:12])
+	copy(s.rawNames[1][:], sidedef[12:20])
+	copy(s.rawNames[2][:], sidedef[20:28])
+	return s
+}
 
 func ReadVertexFrom(r io.ReaderAt, offset int64) (Vertex, int64) {
+	vertex := make([]byte, 4)
 	r.ReadAt(vertex, offset)
-	v := Vertex{
+	return decodeVertex(vertex), offset + 4
+}
+
+func decodeVertex(vertex []byte) Vertex {
+	return Vertex{
 		X: int16(vertex[0]) | int16(vertex[1])<<8,
 		Y: -(int16(vertex[2]) | int16(vertex[3])<<8),
 	}
-
-	return v, offset + 4
 }
 
-var sector = make([]byte, 26)
-
 func ReadSectorFrom(r io.ReaderAt, offset int64) (Sector, int64) {
+	sector := make([]byte, 26)
 	r.ReadAt(sector, offset)
+	return decodeSector(sector), offset + 26
+}
+
+func decodeSector(sector []byte) Sector {
 	s := Sector{
 		FloorHeight:    int16(sector[0]) | int16(sector[1])<<8,
 		CeilingHeight:  int16(sector[2]) | int16(sector[3])<<8,
-		FloorTexture:   string(sector[4:12]),
-		CeilingTexture: string(sector[12:20]),
+		FloorTexture:   trimNUL(sector[4:12]),
+		CeilingTexture: trimNUL(sector[12:20]),
 		LightLevel:     binary.LittleEndian.Uint16(sector[20:22]),
 		SectorType:     binary.LittleEndian.Uint16(sector[22:24]),
 		TagNumber:      binary.LittleEndian.Uint16(sector[24:26]),
 	}
-	return s, offset + 26
+	copy(s.rawNames[0][:], sector[4:12])
+	copy(s.rawNames[1][:], sector[12:20])
+	return s
 }
 
-var thing = make([]byte, 10)
-
 func ReadThingFrom(r io.ReaderAt, offset int64) (Thing, int64) {
+	thing := make([]byte, 10)
 	r.ReadAt(thing, offset)
-	t := Thing{
+	return decodeThing(thing), offset + 10
+}
+
+func decodeThing(thing []byte) Thing {
+	return Thing{
 		XPosition: int16(thing[0]) | int16(thing[1])<<8,
 		YPosition: -(int16(thing[2]) | int16(thing[3])<<8),
 		Angle:     binary.LittleEndian.Uint16(thing[4:6]),
 		ThingType: binary.LittleEndian.Uint16(thing[6:8]),
 		Flags:     binary.LittleEndian.Uint16(thing[8:10]),
 	}
-	return t, offset + 10
 }
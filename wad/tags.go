@@ -0,0 +1,73 @@
+package wad
+
+// lineTaggedSpecials are the Boom specials whose tag picks out other
+// linedefs rather than sectors.
+var lineTaggedSpecials = map[uint16]bool{
+	218: true, 243: true, 244: true, 249: true, 254: true, 260: true,
+	262: true, 263: true, 264: true, 265: true, 266: true, 267: true,
+}
+
+// TagsLineDefs reports whether the special's tag picks out linedefs rather
+// than sectors.
+func (s Special) TagsLineDefs() bool {
+	return lineTaggedSpecials[s.Type]
+}
+
+// Action is what activating a linedef's special affects: the sectors or
+// linedefs sharing its tag, or for a manual door, the sector behind it.
+type Action struct {
+	LineDef  int
+	Special  Special
+	Manual   bool
+	Sectors  []int
+	LineDefs []int
+}
+
+// Actions builds the map's trigger graph, with one action for each linedef
+// that has a special and something for it to act on. Specials that are not
+// known are kept, with only their type filled in.
+func (m *Map) Actions() []Action {
+	sectorsByTag := make(map[uint16][]int)
+	for i, s := range m.Sectors {
+		if s.TagNumber != 0 {
+			sectorsByTag[s.TagNumber] = append(sectorsByTag[s.TagNumber], i)
+		}
+	}
+	lineDefsByTag := make(map[uint16][]int)
+	for i, l := range m.LineDefs {
+		if l.SectorTag != 0 {
+			lineDefsByTag[l.SectorTag] = append(lineDefsByTag[l.SectorTag], i)
+		}
+	}
+	actions := make([]Action, 0)
+	for i, l := range m.LineDefs {
+		if l.SpecialType == 0 {
+			continue
+		}
+		special, ok := l.Special()
+		if !ok {
+			special = Special{Type: l.SpecialType}
+		}
+		a := Action{LineDef: i, Special: special}
+		switch {
+		case special.Trigger == D1 || special.Trigger == DR:
+			a.Manual = true
+			if _, left := m.LineDefSectors(l); left >= 0 && left < len(m.Sectors) {
+				a.Sectors = []int{left}
+			}
+		case l.SectorTag == 0:
+		case special.TagsLineDefs():
+			for _, j := range lineDefsByTag[l.SectorTag] {
+				if j != i {
+					a.LineDefs = append(a.LineDefs, j)
+				}
+			}
+		default:
+			a.Sectors = sectorsByTag[l.SectorTag]
+		}
+		if len(a.Sectors) > 0 || len(a.LineDefs) > 0 {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
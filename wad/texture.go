@@ -0,0 +1,200 @@
+package wad
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TexturePatch places a patch within a composite wall texture.
+type TexturePatch struct {
+	OriginX int16
+	OriginY int16
+	Patch   string
+}
+
+// Texture is a wall texture defined in TEXTURE1 or TEXTURE2, built up from
+// one or more patches.
+type Texture struct {
+	Name    string
+	Width   int
+	Height  int
+	Patches []TexturePatch
+	Lump    string
+}
+
+// ReadPatchNames decodes a PNAMES lump.
+func ReadPatchNames(data []byte) ([]string, error) {
+	if len(data) < 4 {
+		return nil, errors.New("PNAMES lump is too short")
+	}
+	count := int(binary.LittleEndian.Uint32(data[0:4]))
+	if len(data) < 4+8*count {
+		return nil, fmt.Errorf("PNAMES lump is too short for %d names", count)
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = trimName(data[4+8*i : 12+8*i])
+	}
+	return names, nil
+}
+
+// ReadTextures decodes a TEXTURE1 or TEXTURE2 lump, resolving patch numbers
+// through the names from PNAMES. Both the Doom layout and the shorter one
+// used by Strife are understood.
+func ReadTextures(data []byte, patchNames []string) ([]Texture, error) {
+	if len(data) < 4 {
+		return nil, errors.New("texture lump is too short")
+	}
+	count := int(binary.LittleEndian.Uint32(data[0:4]))
+	if count < 0 || len(data) < 4+4*count {
+		return nil, fmt.Errorf("texture lump is too short for %d textures", count)
+	}
+	offsets := make([]int, count)
+	for i := range offsets {
+		offsets[i] = int(binary.LittleEndian.Uint32(data[4+4*i:]))
+	}
+	// Doom entries have a 22 byte header and 10 byte patches, Strife drops
+	// the unused column directory and patch fields for 18 and 6 bytes.
+	header, patchSize, countAt := 22, 10, 20
+	if !textureLayoutFits(data, offsets, header, patchSize, countAt) && textureLayoutFits(data, offsets, 18, 6, 16) {
+		header, patchSize, countAt = 18, 6, 16
+	}
+	textures := make([]Texture, 0, count)
+	for _, offset := range offsets {
+		if offset+header > len(data) {
+			return nil, fmt.Errorf("texture at offset %d runs past the end of the lump", offset)
+		}
+		entry := data[offset:]
+		t := Texture{
+			Name:   trimName(entry[0:8]),
+			Width:  int(binary.LittleEndian.Uint16(entry[12:14])),
+			Height: int(binary.LittleEndian.Uint16(entry[14:16])),
+		}
+		numPatches := int(binary.LittleEndian.Uint16(entry[countAt : countAt+2]))
+		if offset+header+patchSize*numPatches > len(data) {
+			return nil, fmt.Errorf("texture %s runs past the end of the lump", t.Name)
+		}
+		t.Patches = make([]TexturePatch, 0, numPatches)
+		for p := 0; p < numPatches; p++ {
+			patch := entry[header+patchSize*p:]
+			index := int(binary.LittleEndian.Uint16(patch[4:6]))
+			if index >= len(patchNames) {
+				return nil, fmt.Errorf("texture %s uses patch %d, but PNAMES only has %d", t.Name, index, len(patchNames))
+			}
+			t.Patches = append(t.Patches, TexturePatch{
+				OriginX: int16(binary.LittleEndian.Uint16(patch[0:2])),
+				OriginY: int16(binary.LittleEndian.Uint16(patch[2:4])),
+				Patch:   patchNames[index],
+			})
+		}
+		textures = append(textures, t)
+	}
+	return textures, nil
+}
+
+// textureLayoutFits reports whether every texture entry, read with the given
+// layout, lies within the lump and ends where the next one starts.
+func textureLayoutFits(data []byte, offsets []int, header int, patchSize int, countAt int) bool {
+	for i, offset := range offsets {
+		if offset < 0 || offset+header > len(data) {
+			return false
+		}
+		end := offset + header + patchSize*int(binary.LittleEndian.Uint16(data[offset+countAt:]))
+		if end > len(data) {
+			return false
+		}
+		if i+1 < len(offsets) && offsets[i+1] > offset && end != offsets[i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+// Textures returns the wall textures defined in TEXTURE1 and TEXTURE2.
+func (d *Directory) Textures() ([]Texture, error) {
+	l := d.Find("PNAMES")
+	if l == nil {
+		return nil, errors.New("no PNAMES lump")
+	}
+	patchNames, err := ReadPatchNames(d.ReadLump(l))
+	if err != nil {
+		return nil, err
+	}
+	textures := make([]Texture, 0)
+	for _, name := range []string{"TEXTURE1", "TEXTURE2"} {
+		l := d.Find(name)
+		if l == nil {
+			continue
+		}
+		t, err := ReadTextures(d.ReadLump(l), patchNames)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for i := range t {
+			t[i].Lump = name
+		}
+		textures = append(textures, t...)
+	}
+	if len(textures) == 0 {
+		return nil, errors.New("no TEXTURE1 or TEXTURE2 lump")
+	}
+	return textures, nil
+}
+
+// Patch returns the named patch lump, or nil if there is none. Patches
+// between P_START and P_END take precedence over lumps elsewhere. Names are
+// compared without regard to case.
+func (d *Directory) Patch(name string) *LumpPtr {
+	name = strings.ToUpper(name)
+	patches := d.Namespace("P")
+	for i := len(patches) - 1; i >= 0; i-- {
+		if patches[i].Name() == name {
+			return patches[i]
+		}
+	}
+	return d.Find(name)
+}
+
+// Composite draws the patches of t into a single picture. Patches that
+// cannot be found or decoded are left out.
+func (d *Directory) Composite(t *Texture) (*Picture, error) {
+	if t.Width <= 0 || t.Height <= 0 {
+		return nil, fmt.Errorf("texture %s has invalid dimensions %dx%d", t.Name, t.Width, t.Height)
+	}
+	c := &Picture{
+		Width:  t.Width,
+		Height: t.Height,
+		Pixels: make([]byte, t.Width*t.Height),
+		Opaque: make([]bool, t.Width*t.Height),
+	}
+	patches := make(map[string]*Picture)
+	for _, tp := range t.Patches {
+		p, ok := patches[tp.Patch]
+		if !ok {
+			if l := d.Patch(tp.Patch); l != nil {
+				p, _ = DecodePicture(d.ReadLump(l))
+			}
+			patches[tp.Patch] = p
+		}
+		if p == nil {
+			continue
+		}
+		for y := 0; y < p.Height; y++ {
+			ty := int(tp.OriginY) + y
+			if ty < 0 || ty >= c.Height {
+				continue
+			}
+			for x := 0; x < p.Width; x++ {
+				tx := int(tp.OriginX) + x
+				if tx < 0 || tx >= c.Width || !p.Opaque[y*p.Width+x] {
+					continue
+				}
+				c.Pixels[ty*c.Width+tx] = p.Pixels[y*p.Width+x]
+				c.Opaque[ty*c.Width+tx] = true
+			}
+		}
+	}
+	return c, nil
+}
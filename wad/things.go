@@ -0,0 +1,118 @@
+package wad
+
+import "fmt"
+
+// ThingInfo describes a type of thing from the Doom and Doom II games.
+type ThingInfo struct {
+	Name   string
+	Sprite string
+	Radius int
+}
+
+var thingInfo = map[uint16]ThingInfo{
+	1:    {"Player 1 start", "PLAY", 16},
+	2:    {"Player 2 start", "PLAY", 16},
+	3:    {"Player 3 start", "PLAY", 16},
+	4:    {"Player 4 start", "PLAY", 16},
+	5:    {"Blue keycard", "BKEY", 20},
+	6:    {"Yellow keycard", "YKEY", 20},
+	7:    {"Spiderdemon", "SPID", 128},
+	8:    {"Backpack", "BPAK", 20},
+	9:    {"Shotgun guy", "SPOS", 20},
+	11:   {"Deathmatch start", "", 16},
+	13:   {"Red keycard", "RKEY", 20},
+	14:   {"Teleport landing", "", 20},
+	16:   {"Cyberdemon", "CYBR", 40},
+	17:   {"Energy cell pack", "CELP", 20},
+	38:   {"Red skull key", "RSKU", 20},
+	39:   {"Yellow skull key", "YSKU", 20},
+	40:   {"Blue skull key", "BSKU", 20},
+	58:   {"Spectre", "SARG", 30},
+	64:   {"Arch-vile", "VILE", 20},
+	65:   {"Heavy weapon dude", "CPOS", 20},
+	66:   {"Revenant", "SKEL", 20},
+	67:   {"Mancubus", "FATT", 48},
+	68:   {"Arachnotron", "BSPI", 64},
+	69:   {"Hell knight", "BOS2", 24},
+	71:   {"Pain elemental", "PAIN", 31},
+	72:   {"Commander Keen", "KEEN", 16},
+	82:   {"Super shotgun", "SGN2", 20},
+	83:   {"Megasphere", "MEGA", 20},
+	84:   {"Wolfenstein SS", "SSWV", 20},
+	2001: {"Shotgun", "SHOT", 20},
+	2002: {"Chaingun", "MGUN", 20},
+	2003: {"Rocket launcher", "LAUN", 20},
+	2004: {"Plasma gun", "PLAS", 20},
+	2005: {"Chainsaw", "CSAW", 20},
+	2006: {"BFG9000", "BFUG", 20},
+	2007: {"Clip", "CLIP", 20},
+	2008: {"4 shotgun shells", "SHEL", 20},
+	2010: {"Rocket", "ROCK", 20},
+	2011: {"Stimpack", "STIM", 20},
+	2012: {"Medikit", "MEDI", 20},
+	2013: {"Supercharge", "SOUL", 20},
+	2014: {"Health bonus", "BON1", 20},
+	2015: {"Armor bonus", "BON2", 20},
+	2018: {"Armor", "ARM1", 20},
+	2019: {"Megaarmor", "ARM2", 20},
+	2022: {"Invulnerability", "PINV", 20},
+	2023: {"Berserk", "PSTR", 20},
+	2024: {"Partial invisibility", "PINS", 20},
+	2025: {"Radiation shielding suit", "SUIT", 20},
+	2026: {"Computer area map", "PMAP", 20},
+	2045: {"Light amplification visor", "PVIS", 20},
+	2046: {"Box of rockets", "BROK", 20},
+	2047: {"Energy cell", "CELL", 20},
+	2048: {"Box of bullets", "AMMO", 20},
+	2049: {"Box of shotgun shells", "SBOX", 20},
+	3001: {"Imp", "TROO", 20},
+	3002: {"Demon", "SARG", 30},
+	3003: {"Baron of Hell", "BOSS", 24},
+	3004: {"Zombieman", "POSS", 20},
+	3005: {"Cacodemon", "HEAD", 31},
+	3006: {"Lost soul", "SKUL", 16},
+}
+
+// Info returns what is known about the type of the thing.
+func (t *Thing) Info() (ThingInfo, bool) {
+	info, ok := thingInfo[t.ThingType]
+	return info, ok
+}
+
+// Name returns the name of the type of the thing, or its number if the type
+// is not known.
+func (t *Thing) Name() string {
+	if info, ok := thingInfo[t.ThingType]; ok {
+		return info.Name
+	}
+	return fmt.Sprintf("Thing type %d", t.ThingType)
+}
+
+// monsterHealth is how many hit points each monster spawns with.
+var monsterHealth = map[uint16]int{
+	7:    3000,
+	9:    30,
+	16:   4000,
+	58:   150,
+	64:   700,
+	65:   70,
+	66:   300,
+	67:   600,
+	68:   500,
+	69:   500,
+	71:   400,
+	72:   100,
+	84:   50,
+	3001: 60,
+	3002: 150,
+	3003: 1000,
+	3004: 20,
+	3005: 400,
+	3006: 100,
+}
+
+// Health returns the hit points the thing spawns with, or 0 if it is not a
+// monster.
+func (t *Thing) Health() int {
+	return monsterHealth[t.ThingType]
+}
@@ -0,0 +1,175 @@
+package wad
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Lump is a named block of data to be written to a WAD.
+type Lump struct {
+	Name string
+	Data []byte
+}
+
+// putName writes a lump, texture or flat name as eight NUL padded bytes.
+func putName(b []byte, name string) {
+	for i := range b[:8] {
+		b[i] = 0
+	}
+	copy(b[:8], name)
+}
+
+// putRawName writes a name as it was read, bytes after any NUL included, if
+// it has not been changed since, and otherwise as putName does.
+func putRawName(b []byte, name string, raw [8]byte) {
+	if trimNUL(raw[:]) == name {
+		copy(b[:8], raw[:])
+		return
+	}
+	putName(b, name)
+}
+
+// WriteWAD writes the lumps as a WAD file with the given identification,
+// IWAD or PWAD: the header, then the lump data, then the directory.
+func WriteWAD(w io.Writer, identification string, lumps []Lump) error {
+	if len(identification) != 4 {
+		return fmt.Errorf("invalid WAD identification %q", identification)
+	}
+	offset := 12
+	for _, l := range lumps {
+		offset += len(l.Data)
+	}
+	header := make([]byte, 12)
+	copy(header, identification)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(lumps)))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(offset))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	for _, l := range lumps {
+		if _, err := w.Write(l.Data); err != nil {
+			return err
+		}
+	}
+	entry := make([]byte, 16)
+	position := 12
+	for _, l := range lumps {
+		if len(l.Name) > 8 {
+			return fmt.Errorf("lump name %q is longer than 8 characters", l.Name)
+		}
+		binary.LittleEndian.PutUint32(entry[0:4], uint32(position))
+		binary.LittleEndian.PutUint32(entry[4:8], uint32(len(l.Data)))
+		putName(entry[8:16], l.Name)
+		if _, err := w.Write(entry); err != nil {
+			return err
+		}
+		position += len(l.Data)
+	}
+	return nil
+}
+
+// Lumps encodes the map as its marker lump followed by the map lumps that
+// are read into a Map, in the order Doom expects them. The nodes, REJECT
+// and BLOCKMAP are not written, and m.BlockMap, which only holds the
+// BLOCKMAP's header, is dropped, so a node builder must be run on the map
+// before it can be played.
+func (m *Map) Lumps(mapName string) []Lump {
+	return []Lump{
+		{mapName, nil},
+		{"THINGS", m.thingsLump()},
+		{"LINEDEFS", m.lineDefsLump()},
+		{"SIDEDEFS", m.sideDefsLump()},
+		{"VERTEXES", m.vertexesLump()},
+		{"SECTORS", m.sectorsLump()},
+	}
+}
+
+// WriteMap saves the map on its own as a PWAD.
+func (m *Map) WriteMap(w io.Writer, mapName string) error {
+	return WriteWAD(w, "PWAD", m.Lumps(mapName))
+}
+
+func (m *Map) thingsLump() []byte {
+	data := make([]byte, len(m.Things)*10)
+	for i, t := range m.Things {
+		t.encode(data[i*10 : i*10+10])
+	}
+	return data
+}
+
+func (m *Map) lineDefsLump() []byte {
+	data := make([]byte, len(m.LineDefs)*14)
+	for i, l := range m.LineDefs {
+		l.encode(data[i*14 : i*14+14])
+	}
+	return data
+}
+
+func (m *Map) sideDefsLump() []byte {
+	data := make([]byte, len(m.SideDefs)*30)
+	for i, s := range m.SideDefs {
+		s.encode(data[i*30 : i*30+30])
+	}
+	return data
+}
+
+func (m *Map) vertexesLump() []byte {
+	data := make([]byte, len(m.Vertexes)*4)
+	for i, v := range m.Vertexes {
+		v.encode(data[i*4 : i*4+4])
+	}
+	return data
+}
+
+func (m *Map) sectorsLump() []byte {
+	data := make([]byte, len(m.Sectors)*26)
+	for i, s := range m.Sectors {
+		s.encode(data[i*26 : i*26+26])
+	}
+	return data
+}
+
+// encode is the reverse of ReadThingFrom, flipping Y back to point north.
+func (t Thing) encode(b []byte) {
+	binary.LittleEndian.PutUint16(b[0:2], uint16(t.XPosition))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(-t.YPosition))
+	binary.LittleEndian.PutUint16(b[4:6], t.Angle)
+	binary.LittleEndian.PutUint16(b[6:8], t.ThingType)
+	binary.LittleEndian.PutUint16(b[8:10], t.Flags)
+}
+
+func (l LineDef) encode(b []byte) {
+	binary.LittleEndian.PutUint16(b[0:2], l.Start)
+	binary.LittleEndian.PutUint16(b[2:4], l.End)
+	binary.LittleEndian.PutUint16(b[4:6], l.Flags)
+	binary.LittleEndian.PutUint16(b[6:8], l.SpecialType)
+	binary.LittleEndian.PutUint16(b[8:10], l.SectorTag)
+	binary.LittleEndian.PutUint16(b[10:12], l.RightSideDef)
+	binary.LittleEndian.PutUint16(b[12:14], l.LeftSideDef)
+}
+
+func (s SideDef) encode(b []byte) {
+	binary.LittleEndian.PutUint16(b[0:2], uint16(s.XOffset))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(s.YOffset))
+	putRawName(b[4:12], s.UpperTextureName, s.rawNames[0])
+	putRawName(b[12:20], s.LowerTextureName, s.rawNames[1])
+	putRawName(b[20:28], s.MiddleTextureName, s.rawNames[2])
+	binary.LittleEndian.PutUint16(b[28:30], s.SectorNumber)
+}
+
+// encode is the reverse of ReadVertexFrom, flipping Y back to point north.
+func (v Vertex) encode(b []byte) {
+	binary.LittleEndian.PutUint16(b[0:2], uint16(v.X))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(-v.Y))
+}
+
+func (s Sector) encode(b []byte) {
+	binary.LittleEndian.PutUint16(b[0:2], uint16(s.FloorHeight))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(s.CeilingHeight))
+	putRawName(b[4:12], s.FloorTexture, s.rawNames[0])
+	putRawName(b[12:20], s.CeilingTexture, s.rawNames[1])
+	binary.LittleEndian.PutUint16(b[20:22], s.LightLevel)
+	binary.LittleEndian.PutUint16(b[22:24], s.SectorType)
+	binary.LittleEndian.PutUint16(b[24:26], s.TagNumber)
+}
@@ -0,0 +1,109 @@
+package wad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// name8 returns a name as the eight bytes stored in a lump.
+func name8(name string) []byte {
+	b := make([]byte, 8)
+	copy(b, name)
+	return b
+}
+
+func le16(values ...int) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// testMapLumps returns the lumps of a small map: a square room split in two
+// by a two sided linedef, with names in mixed case, names with bytes after
+// their NUL, and a vertex at the far edge of the coordinate range.
+func testMapLumps(mapName string) []Lump {
+	return []Lump{
+		{mapName, nil},
+		{"THINGS", join(
+			le16(32, -32, 90, 1, 7),
+			le16(96, -32, 180, 3001, 4),
+		)},
+		{"LINEDEFS", join(
+			le16(0, 1, 1, 0, 0, 0, 0xffff),
+			le16(1, 2, 1, 0, 0, 1, 0xffff),
+			le16(2, 3, 1, 0, 0, 2, 0xffff),
+			le16(3, 0, 1, 0, 0, 3, 0xffff),
+			le16(4, 5, 4, 1, 7, 4, 5),
+		)},
+		{"SIDEDEFS", join(
+			le16(0, 0), name8("-"), name8("-"), name8("STARTAN3"), le16(0),
+			le16(16, -8), name8("-"), name8("-"), name8("startan3"), le16(0),
+			le16(0, 0), name8("-"), name8("-"), []byte("BROWN1\x00X"), le16(1),
+			le16(0, 0), name8("-"), name8("-"), name8("BIGDOOR1"), le16(1),
+			le16(0, 0), name8("Step1"), name8("STEP2"), name8("-"), le16(0),
+			le16(0, 0), name8("-"), name8("-"), name8("-"), le16(1),
+		)},
+		{"VERTEXES", le16(0, 0, 128, 0, 128, -32768, 0, -128, 64, 0, 64, -128)},
+		{"SECTORS", join(
+			le16(0, 128), name8("FLOOR4_8"), name8("ceil3_5"), le16(160, 0, 0),
+			le16(16, 128), []byte("NUKAGE1\x00"), []byte("F_SKY1\x00\xff"), le16(255, 9, 7),
+		)},
+	}
+}
+
+func TestWriteMapRoundTrip(t *testing.T) {
+	lumps := testMapLumps("MAP01")
+	buf := &bytes.Buffer{}
+	if err := WriteWAD(buf, "PWAD", lumps); err != nil {
+		t.Fatalf("WriteWAD: %v", err)
+	}
+	m := &Map{}
+	m.ReadFrom(bytes.NewReader(buf.Bytes()), "MAP01")
+	if len(m.Sectors) != 2 {
+		t.Fatalf("read %d sectors, want 2", len(m.Sectors))
+	}
+	out := &bytes.Buffer{}
+	if err := m.WriteMap(out, "MAP01"); err != nil {
+		t.Fatalf("WriteMap: %v", err)
+	}
+	d, err := readDirectory(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("reading the written WAD: %v", err)
+	}
+	for _, want := range lumps {
+		l := d.Find(want.Name)
+		if l == nil {
+			t.Errorf("lump %s was not written", want.Name)
+			continue
+		}
+		if got := d.ReadLump(l); !bytes.Equal(got, want.Data) {
+			t.Errorf("lump %s = %x, want %x", want.Name, got, want.Data)
+		}
+	}
+}
+
+func TestWriteMapKeepsEditedNames(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := WriteWAD(buf, "PWAD", testMapLumps("MAP01")); err != nil {
+		t.Fatalf("WriteWAD: %v", err)
+	}
+	m := &Map{}
+	m.ReadFrom(bytes.NewReader(buf.Bytes()), "MAP01")
+	m.SideDefs[2].MiddleTextureName = "BROWN96"
+	m.Sectors[1].CeilingTexture = "F_SKY"
+	data := m.sideDefsLump()
+	if got, want := data[2*30+20:2*30+28], name8("BROWN96"); !bytes.Equal(got, want) {
+		t.Errorf("edited texture written as %q, want %q", got, want)
+	}
+	data = m.sectorsLump()
+	if got, want := data[26+12:26+20], name8("F_SKY"); !bytes.Equal(got, want) {
+		t.Errorf("edited flat written as %q, want %q", got, want)
+	}
+}